@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/alsbury/spice2json/pkg/spice2json"
+)
+
+// namespaceRule sets the default namespace of the batch files matching the glob pattern, or
+// below the directory for a pattern without glob characters
+type namespaceRule struct {
+	Pattern   string
+	Namespace string
+}
+
+// namespaceMapping is the -n value, the default namespace and the per file rules of -batch
+type namespaceMapping struct {
+	Default string
+	Rules   []namespaceRule
+}
+
+// parseNamespaceMapping reads -n, either one namespace for all files or comma separated
+// pattern=namespace rules, e.g. billing/*.zed=billing,auth=auth. A pattern is a glob or a
+// directory, each only once. An entry without = is the namespace of the files no rule matches.
+func parseNamespaceMapping(value string) (namespaceMapping, error) {
+	var mapping namespaceMapping
+	if !strings.Contains(value, "=") {
+		mapping.Default = value
+		return mapping, nil
+	}
+	hasDefault := false
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		pattern, namespace, ok := strings.Cut(entry, "=")
+		if !ok {
+			if hasDefault {
+				return mapping, fmt.Errorf("more than one default namespace in -n, %q and %q", mapping.Default, entry)
+			}
+			hasDefault = true
+			mapping.Default = entry
+			continue
+		}
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if pattern == "" || strings.Contains(namespace, "=") {
+			return mapping, fmt.Errorf("invalid namespace rule %q in -n, want pattern=namespace", entry)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return mapping, fmt.Errorf("invalid namespace glob %q in -n", pattern)
+		}
+		if seen[pattern] {
+			return mapping, fmt.Errorf("namespace rule for %q given more than once in -n", pattern)
+		}
+		seen[pattern] = true
+		mapping.Rules = append(mapping.Rules, namespaceRule{Pattern: pattern, Namespace: namespace})
+	}
+	return mapping, nil
+}
+
+// namespace returns the namespace of the longest rule matching the file, by its path below the
+// input directory or as read, or the default namespace
+func (m namespaceMapping) namespace(input string, file string) string {
+	paths := []string{filepath.ToSlash(file)}
+	if rel, err := filepath.Rel(input, file); err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	namespace := m.Default
+	longest := -1
+	for _, rule := range m.Rules {
+		for _, path := range paths {
+			if rule.matches(path) && len(rule.Pattern) > longest {
+				namespace = rule.Namespace
+				longest = len(rule.Pattern)
+			}
+		}
+	}
+	return namespace
+}
+
+// matches reports whether the slash separated path matches the glob, or is below the directory
+func (r namespaceRule) matches(path string) bool {
+	if !strings.ContainsAny(r.Pattern, "*?[") {
+		return strings.HasPrefix(path, r.Pattern+"/")
+	}
+	ok, _ := filepath.Match(r.Pattern, path)
+	return ok
+}
+
+// batchOutputName is the output path of a batch input file, its path below the input
+// directory, or its base name for a glob, with the format as extension
+func batchOutputName(input string, file string, outDir string, format string) string {
+	name := filepath.Base(file)
+	if rel, err := filepath.Rel(input, file); err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+		name = rel
+	}
+	name = strings.TrimSuffix(name, ".gz")
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return filepath.Join(outDir, name+"."+format)
+}
+
+// convertBatch converts each file on its own, with as many workers as GOMAXPROCS, and writes
+// it to its own output file in outDir. All failed files are returned, in file order.
+func convertBatch(input string, files []spice2json.SourceFile, outDir string, namespaces namespaceMapping, opts spice2json.Options, format string, finalNewline bool) []error {
+	errs := make([]error, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range runtime.GOMAXPROCS(0) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = convertBatchFile(files[i], batchOutputName(input, files[i].Name, outDir, format), namespaces.namespace(input, files[i].Name), opts, format, finalNewline)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+func convertBatchFile(file spice2json.SourceFile, output string, namespace string, opts spice2json.Options, format string, finalNewline bool) error {
+	converted, err := spice2json.ConvertFrom(file.Name, spice2json.StripImports(file.Source), namespace, opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return err
+	}
+	outFile, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	out := withFinalNewline(outFile, finalNewline)
+	err = spice2json.WriteAs(converted, format, out, opts)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// readBatchFiles reads the schema files of a directory or glob pattern for -batch
+func readBatchFiles(input string) []spice2json.SourceFile {
+	if strings.ContainsAny(input, "*?[") {
+		return readSchemaFromGlob(input)
+	}
+	if info, err := os.Stat(input); err != nil || !info.IsDir() {
+		exitWithError(usageError(errors.New("-batch requires a directory or glob pattern as input")))
+	}
+	return readSchemaFromDir(input)
+}
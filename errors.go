@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+)
+
+// errorFormat is set by -error-format, text or json
+var errorFormat = "text"
+
+// errorOutput is an error written with -error-format json
+type errorOutput struct {
+	Error  string `json:"error"`
+	Source string `json:"source,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// Exit codes, so scripts can tell e.g. a missing file from a broken schema
+const (
+	exitUsage      = 1
+	exitIO         = 2
+	exitCompile    = 3
+	exitConversion = 4
+	exitLint       = 5
+)
+
+// exitError is an error with the exit code it ends spice2json with
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitError) Unwrap() error {
+	return e.err
+}
+
+// usageError is a wrong flag or argument
+func usageError(err error) error {
+	return exitError{code: exitUsage, err: err}
+}
+
+// ioError is a failure reading the schema or writing the output, other than the file errors
+// already recognized by exitCode
+func ioError(err error) error {
+	return exitError{code: exitIO, err: err}
+}
+
+// compileError is a broken schema found before or outside the compiler
+func compileError(err error) error {
+	return exitError{code: exitCompile, err: err}
+}
+
+// exitCode returns the exit code of the error: the code of an exitError, exitCompile for
+// compiler errors, exitIO for file errors and exitConversion for everything else
+func exitCode(err error) int {
+	var exitErr exitError
+	var compileErr compiler.ErrorWithContext
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &compileErr):
+		return exitCompile
+	case errors.As(err, &pathErr):
+		return exitIO
+	default:
+		return exitConversion
+	}
+}
+
+// exitWithError prints the error to stderr and exits with its exit code
+func exitWithError(err error) {
+	printError(err)
+	os.Exit(exitCode(err))
+}
+
+// printError prints the error to stderr. In the json error format the source, line and column
+// are taken from compiler errors and the path from file errors.
+func printError(err error) {
+	if errorFormat != "json" {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	output := errorOutput{Error: err.Error()}
+	var compileErr compiler.ErrorWithContext
+	var pathErr *fs.PathError
+	if errors.As(err, &compileErr) {
+		output.Error = compileErr.BaseMessage
+		output.Source = string(compileErr.Source)
+		if line, column, err := compileErr.SourceRange.Start().LineAndColumn(); err == nil {
+			output.Line = line + 1
+			output.Column = column + 1
+		}
+	} else if errors.As(err, &pathErr) {
+		output.Source = pathErr.Path
+	}
+
+	data, _ := json.Marshal(output)
+	fmt.Fprintln(os.Stderr, string(data))
+}
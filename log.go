@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alsbury/spice2json/pkg/spice2json"
+)
+
+// verbosity is how much is printed to stderr besides errors
+type verbosity int
+
+const (
+	// verbosityQuiet only prints errors, set by -quiet
+	verbosityQuiet verbosity = iota
+	// verbosityNormal also prints warnings, the conversion summary and -watch status
+	verbosityNormal
+)
+
+// logLevel is set by -quiet, like errorFormat it applies to everything written to stderr
+var logLevel = verbosityNormal
+
+// printWarning prints the warning to stderr unless -quiet is set
+func printWarning(w spice2json.Warning) {
+	if logLevel >= verbosityNormal {
+		fmt.Fprintln(os.Stderr, "warning: "+w.String())
+	}
+}
+
+// printInfo prints a status line to stderr unless -quiet is set
+func printInfo(format string, args ...any) {
+	if logLevel >= verbosityNormal {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
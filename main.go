@@ -1,55 +1,243 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"runtime/debug"
+	"slices"
 	"strings"
 
-	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/alsbury/spice2json/pkg/spice2json"
 )
 
-const VERSION = "0.3.1"
+// VERSION is the release version, set at build time with -ldflags "-X main.VERSION=..."
+var VERSION = "0.3.1"
 
 func main() {
-	namespace := flag.String("n", "", "default namespace")
+	// flag errors exit with exitUsage instead of the flag package's 2, which is exitIO here
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	namespace := flag.String("n", "", "default namespace for definitions and caveats without a namespace/ prefix, or pattern=namespace rules for -batch")
 	version := flag.Bool("v", false, "print version and exit")
+	versionInfo := flag.Bool("version", false, "print spice2json and spicedb library versions and exit")
 	stdIn := flag.Bool("s", false, "read schema from stdin rather than a file")
 	readFile := flag.Bool("f", false, "read schema from file (default)")
 	readRest := flag.Bool("h", false, "read from spicedb http url to retrieve schema")
 	readGrpc := flag.Bool("g", false, "read from spicedb grpc host + port to retrieve schema")
 	insecureGrpc := flag.Bool("insecure", false, "connect to non TLS grpc host")
 	key := flag.String("k", "", "pre-shared key for rest / grpc schema")
-	flag.Parse()
+	endpoint := flag.String("endpoint", "", "spicedb grpc host + port to read the schema from, instead of an input argument")
+	token := flag.String("token", "", "pre-shared key for -endpoint, same as -k")
+	outputFile := flag.String("o", "", "write output to file, use - for stdout")
+	sortOutput := flag.Bool("sort", false, "sort definitions, relations, permissions and caveats by name")
+	format := flag.String("format", "json", "output format, json, ndjson, yaml, toml, dot, mermaid, plantuml, csv, openapi-fragment, edges, zed or sql")
+	pretty := flag.Bool("pretty", true, "indent json output, use -pretty=false for compact json")
+	indent := flag.String("indent", "  ", "indent used for pretty json, spaces or tabs, \\t is read as a tab")
+	stats := flag.Bool("stats", false, "print schema statistics as json to stdout")
+	lint := flag.Bool("lint", false, "print schema lint warnings to stderr")
+	werror := flag.Bool("Werror", false, "exit non-zero when -lint reports warnings")
+	memberPattern := flag.String("member-pattern", spice2json.DefaultNamingPattern.String(), "regex relation and permission names have to match for the naming lint check")
+	definitionPattern := flag.String("definition-pattern", spice2json.DefaultNamingPattern.String(), "regex definition names without namespace have to match for the naming lint check")
+	checks := flag.String("checks", strings.Join(spice2json.LintChecks(), ","), "comma separated lint checks run by -lint")
+	positions := flag.Bool("positions", false, "include the source line and column of definitions, relations and permissions")
+	rawComments := flag.Bool("raw-comments", false, "keep comments as written, only removing the comment markers")
+	noComments := flag.Bool("no-comments", false, "leave all comments out of the output")
+	parseAnnotations := flag.Bool("parse-annotations", false, "move @key: value and @flag comment lines into annotations")
+	var definitions stringList
+	flag.Var(&definitions, "def", "only output the definition with this name or namespace/name, can be repeated")
+	inlineCaveats := flag.Bool("inline-caveats", false, "include the full caveat definition in relation types requiring a caveat")
+	qualifiedSubjects := flag.Bool("qualified-subjects", false, "add subjectType with the namespace/type#relation subject to relation types")
+	allowEmpty := flag.Bool("allow-empty", false, "allow a schema without any definitions")
+	split := flag.Bool("split", false, "write each definition to its own json file in the output directory or .zip file, with a manifest.json")
+	validate := flag.Bool("validate", false, "check the output against the published json schema before writing it")
+	diff := flag.Bool("diff", false, "compare two schema files and output the changes, as json or with -format text")
+	naming := flag.String("naming", "camel", "json key casing, camel for userSet or snake for user_set")
+	keyed := flag.Bool("map", false, "output definitions and caveats as objects keyed by name instead of arrays")
+	resolveSubjects := flag.Bool("resolve-subjects", false, "add the subject types each permission can ultimately be granted to")
+	orphans := flag.Bool("orphans", false, "mark definitions no relation has as subject type with orphan, a definition referring to itself isn't one")
+	fingerprint := flag.Bool("fingerprint", false, "print a sha-256 fingerprint of the schema independent of declaration order and exit")
+	ignoreComments := flag.Bool("ignore-comments", false, "leave comments out of -fingerprint")
+	raw := flag.Bool("raw", false, "write the compiled schema protos as protojson instead of the simplified schema, for debugging")
+	embedSource := flag.Bool("embed-source", false, "add the schema source to the output, keyed by file name when reading multiple files")
+	faithfulTree := flag.Bool("faithful-tree", false, "keep nil operands in permission user sets, for a lossless round trip back to the schema dsl")
+	caveatAST := flag.Bool("caveat-ast", false, "add the checked cel expression of each caveat as protojson, this enlarges the output a lot")
+	noCaveats := flag.Bool("no-caveats", false, "leave out caveats and the caveat names of relation types and user sets")
+	templateFile := flag.String("template", "", "render the schema with this go text/template file instead of -format")
+	expandWildcards := flag.Bool("expand-wildcards", false, "write wildcard relation types as type and wildcard true, without relation \"*\"")
+	respectExclude := flag.Bool("respect-exclude", false, "leave out definitions with an @exclude line in their doc comment")
+	subjectIndex := flag.String("subject-index", "", "print every type:permission the given subject type can be granted and exit")
+	references := flag.Bool("references", false, "print how often each definition is used as subject type by other definitions and exit")
+	batch := flag.Bool("batch", false, "convert each file of the input directory or glob on its own, in parallel, into the output directory")
+	watch := flag.Bool("watch", false, "convert again whenever the input file or directory changes, until interrupted")
+	quiet := flag.Bool("quiet", false, "only print errors to stderr, no warnings, conversion summary or -watch status")
+	noFinalNewline := flag.Bool("no-final-newline", false, "don't end the output with a newline")
+	skipUnknown := flag.Bool("skip-unknown", false, "warn about and leave out relations that are neither a relation nor a permission")
+	bestEffort := flag.Bool("best-effort", false, "skip definitions that don't compile and warn about undefined subject types and caveats")
+	check := flag.Bool("check", false, "only check that the schema converts, without writing any output")
+	reverse := flag.Bool("reverse", false, "read spice2json json output and write it back as schema dsl")
+	errorFormatFlag := flag.String("error-format", "text", "print errors to stderr as text or json with source, line and column")
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(exitUsage)
+	}
+
+	errorFormat = *errorFormatFlag
+	if *quiet {
+		logLevel = verbosityQuiet
+	}
+	if errorFormat != "text" && errorFormat != "json" {
+		errorFormat = "text"
+		exitWithError(usageError(fmt.Errorf("unknown error format %q, use text or json", *errorFormatFlag)))
+	}
 
 	if *version == true {
 		fmt.Println(VERSION)
 		os.Exit(0)
 	}
 
+	if *versionInfo {
+		fmt.Println("spice2json " + VERSION)
+		fmt.Println("spicedb " + spicedbVersion())
+		os.Exit(0)
+	}
+
+	if *naming != "camel" && *naming != "snake" {
+		exitWithError(usageError(fmt.Errorf("unknown naming %q, use camel or snake", *naming)))
+	}
+	if *naming == "snake" && ((*format != "json" && *format != "ndjson") || *keyed || *split || *raw) {
+		exitWithError(usageError(errors.New("-naming snake only supports json and ndjson output without -map, -split or -raw")))
+	}
+
+	namespaces, err := parseNamespaceMapping(*namespace)
+	if err != nil {
+		exitWithError(usageError(err))
+	}
+	if len(namespaces.Rules) > 0 && !*batch {
+		exitWithError(usageError(errors.New("-n with pattern=namespace rules requires -batch")))
+	}
+	*namespace = namespaces.Default
+
+	// skipped counts the relations left out by -skip-unknown, they are reported while mapping
+	// and fail -lint -Werror with the unknown check
+	skipped := 0
+	opts := spice2json.Options{
+		Positions:         *positions,
+		RawComments:       *rawComments,
+		InlineCaveats:     *inlineCaveats,
+		QualifiedSubjects: *qualifiedSubjects,
+		NoComments:        *noComments,
+		NoCaveats:         *noCaveats,
+		CaveatAST:         *caveatAST,
+		ParseAnnotations:  *parseAnnotations,
+		ExpandWildcards:   *expandWildcards,
+		FaithfulTree:      *faithfulTree,
+		RespectExclude:    *respectExclude,
+		SnakeCaseKeys:     *naming == "snake",
+		SkipUnknown:       *skipUnknown,
+		OnWarning: func(w spice2json.Warning) {
+			printWarning(w)
+			if w.Check == "unknown" {
+				skipped++
+			}
+		},
+	}
+
+	if *diff {
+		if flag.NArg() != 2 {
+			exitWithError(usageError(errors.New("-diff requires the old and the new schema file")))
+		}
+		if *format != "json" && *format != "text" {
+			exitWithError(usageError(fmt.Errorf("unknown diff format %q, use json or text", *format)))
+		}
+		output, err := diffSchemas(flag.Arg(0), flag.Arg(1), *namespace, opts, *format)
+		if err != nil {
+			exitWithError(err)
+		}
+		writeOutput(output, *outputFile)
+		return
+	}
+
+	// the format and indent are checked before createOutput truncates the output file
+	if !slices.Contains(spice2json.Formats(), *format) {
+		exitWithError(usageError(fmt.Errorf("unknown output format %q, use %s", *format, strings.Join(spice2json.Formats(), ", "))))
+	}
+	if *keyed && *format != "json" && *format != "yaml" && *format != "toml" {
+		exitWithError(usageError(fmt.Errorf("-map doesn't support output format %q, use json, yaml or toml", *format)))
+	}
+	if *pretty {
+		if err := spice2json.ValidateIndent(strings.ReplaceAll(*indent, `\t`, "\t")); err != nil {
+			exitWithError(usageError(err))
+		}
+	}
+
+	if *watch {
+		if *stdIn || *endpoint != "" || *readRest || *readGrpc || flag.Arg(0) == "" || flag.Arg(0) == "-" {
+			exitWithError(usageError(errors.New("-watch requires an input file or directory")))
+		}
+		watchSchema(flag.Arg(0))
+		return
+	}
+
+	if *batch {
+		outDir := *outputFile
+		if outDir == "" {
+			outDir = flag.Arg(1)
+		}
+		if flag.Arg(0) == "" || outDir == "" || outDir == "-" {
+			exitWithError(usageError(errors.New("-batch requires an input directory or glob and an output directory")))
+		}
+		batchOpts := opts
+		batchOpts.Indent = ""
+		if *pretty {
+			batchOpts.Indent = strings.ReplaceAll(*indent, `\t`, "\t")
+		}
+		batchOpts.OnWarning = printWarning
+		errs := convertBatch(flag.Arg(0), readBatchFiles(flag.Arg(0)), outDir, namespaces, batchOpts, *format, !*noFinalNewline)
+		for _, err := range errs {
+			printError(err)
+		}
+		if len(errs) > 0 {
+			os.Exit(exitCode(errs[0]))
+		}
+		return
+	}
+
 	var schema string
-	if *stdIn {
+	var sourceFiles []spice2json.SourceFile
+	source := "stdin"
+	if *stdIn || flag.Arg(0) == "-" {
 		stdin, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			panic(err)
+			exitWithError(ioError(err))
+		}
+		schema = readSchemaData(stdin, source)
+	} else if *endpoint != "" {
+		if *token != "" {
+			*key = *token
 		}
-		schema = string(stdin)
+		source = *endpoint
+		schema = readSchemaFromGrpc(*endpoint, *key, *insecureGrpc)
 	} else {
 		inputSrc := flag.Arg(0)
 		if inputSrc == "" {
 			displayUsageInfo()
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
+		source = inputSrc
 
 		if !*readGrpc && !*readRest {
 			*readFile = true
 		}
 
 		if *readFile {
-			schema = readSchemaFromFile(inputSrc)
+			sourceFiles = readSourceFilesFromPath(inputSrc, *namespace)
+			schema, source = joinSourceFiles(sourceFiles)
 		} else if *readRest {
 			schema = readSchemaFromUrl(inputSrc, *key)
 		} else if *readGrpc {
@@ -57,85 +245,411 @@ func main() {
 		}
 	}
 
-	in := compiler.InputSchema{
-		SchemaString: schema,
+	outputFileName := *outputFile
+	if outputFileName == "" {
+		outputFileName = flag.Arg(1)
 	}
 
-	def, err := compiler.Compile(in, compiler.ObjectTypePrefix(*namespace))
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if *reverse {
+		writeOutput(reverseSchema(schema), outputFileName)
+		return
 	}
 
-	var buf strings.Builder
-	err = WriteSchemaTo(def, &buf)
+	if *split {
+		if outputFileName == "" || outputFileName == "-" {
+			exitWithError(usageError(errors.New("-split requires an output directory or .zip file")))
+		}
+		if *format != "json" {
+			exitWithError(usageError(errors.New("-split only supports json output")))
+		}
+	}
+
+	jsonIndent := ""
+	if *pretty {
+		jsonIndent = strings.ReplaceAll(*indent, `\t`, "\t")
+	}
+
+	if *raw {
+		out := withFinalNewline(createOutput(outputFileName), !*noFinalNewline)
+		err := spice2json.WriteRawTo(out, source, schema, *namespace, jsonIndent)
+		if err == nil {
+			err = out.Close()
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	// ndjson and split files are written one definition at a time when nothing needs the whole
+	// converted schema
+	streamed := (*format == "ndjson" && !*split && !*keyed && *templateFile == "") || (*split && !strings.HasSuffix(outputFileName, ".zip"))
+	needsSchema := *bestEffort || *embedSource || *resolveSubjects || *orphans || len(definitions) > 0 || *sortOutput ||
+		*lint || *validate || *check || *fingerprint || *subjectIndex != "" || *references || *stats
+	if streamed && !needsSchema {
+		streamOpts := opts
+		streamOpts.Indent = jsonIndent
+		writeStreamed(source, schema, *namespace, *split, outputFileName, streamOpts, !*noFinalNewline, *allowEmpty)
+		return
+	}
+
+	var converted *spice2json.Schema
+	if *bestEffort {
+		var warnings []spice2json.Warning
+		converted, warnings, err = spice2json.ConvertBestEffort(source, schema, *namespace, opts)
+		for _, w := range warnings {
+			printWarning(w)
+		}
+	} else {
+		converted, err = spice2json.ConvertFrom(source, schema, *namespace, opts)
+	}
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
-	output, _ := PrettyString(buf.String())
+	if len(converted.Definitions) == 0 && !*allowEmpty {
+		exitWithError(fmt.Errorf("schema %s has no object definitions, use -allow-empty to allow it", source))
+	}
 
-	outputFileName := flag.Arg(1)
-	if outputFileName != "" {
-		data := []byte(output)
-		err = os.WriteFile(outputFileName, data, 0644)
+	if *embedSource {
+		if sourceFiles == nil {
+			sourceFiles = []spice2json.SourceFile{{Name: source, Source: schema}}
+		}
+		converted.EmbedSource(sourceFiles)
+	}
+
+	if *resolveSubjects {
+		converted.ResolveSubjects()
+	}
+
+	if *orphans {
+		converted.MarkOrphans()
+	}
+
+	if len(definitions) > 0 {
+		err = converted.FilterDefinitions(definitions)
+		if err != nil {
+			exitWithError(usageError(err))
+		}
+	}
+
+	if *sortOutput {
+		converted.Sort()
+	}
+
+	if *lint {
+		enabled := strings.Split(*checks, ",")
+		memberNames, err := regexp.Compile(*memberPattern)
+		if err != nil {
+			exitWithError(usageError(fmt.Errorf("invalid -member-pattern: %w", err)))
+		}
+		definitionNames, err := regexp.Compile(*definitionPattern)
+		if err != nil {
+			exitWithError(usageError(fmt.Errorf("invalid -definition-pattern: %w", err)))
+		}
+		warnings, err := spice2json.LintWith(converted, spice2json.LintOptions{
+			Checks:          enabled,
+			MemberNames:     memberNames,
+			DefinitionNames: definitionNames,
+		})
+		if err != nil {
+			exitWithError(usageError(err))
+		}
+		for _, w := range warnings {
+			printWarning(w)
+		}
+		failed := len(warnings) > 0 || (slices.Contains(enabled, "unknown") && skipped > 0)
+		if *werror && failed {
+			os.Exit(exitLint)
+		}
+	}
+
+	if *validate {
+		output, err := validateSchema(converted)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, output)
+			exitWithError(exitError{code: exitLint, err: err})
+		}
+	}
+
+	if *check {
+		return
+	}
+
+	if *fingerprint {
+		sum, err := converted.Fingerprint(*ignoreComments)
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Println(sum)
+		return
+	}
+
+	if *subjectIndex != "" {
+		data, _ := json.MarshalIndent(converted.SubjectIndex(*subjectIndex), "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if *references {
+		data, _ := json.MarshalIndent(converted.References(), "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if *stats {
+		data, _ := json.MarshalIndent(converted.Stats(), "", "  ")
+		fmt.Println(string(data))
+		if outputFileName == "" || outputFileName == "-" {
+			return
+		}
+	}
+
+	if *split {
+		if strings.HasSuffix(outputFileName, ".zip") {
+			out := createOutput(outputFileName)
+			_, err = spice2json.WriteSplitZip(converted, out, jsonIndent)
+			if err == nil {
+				err = out.Close()
+			}
+		} else {
+			_, err = spice2json.WriteSplit(converted, outputFileName, jsonIndent)
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+		printSummary(converted)
+		return
+	}
+
+	if *templateFile != "" {
+		source, err := os.ReadFile(*templateFile)
+		if err != nil {
+			exitWithError(err)
+		}
+		out := createOutput(outputFileName)
+		err = spice2json.WriteTemplate(converted, out, *templateFile, string(source))
+		if err == nil {
+			err = out.Close()
+		}
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
+		printSummary(converted)
+		return
+	}
+
+	out := withFinalNewline(createOutput(outputFileName), !*noFinalNewline)
+	if *keyed {
+		err = spice2json.WriteKeyedSchemaIndentTo(converted.Keyed(), out, *format, jsonIndent)
 	} else {
-		fmt.Print(output)
+		outputOpts := opts
+		outputOpts.Indent = jsonIndent
+		err = spice2json.WriteAs(converted, *format, out, outputOpts)
+	}
+	if err == nil {
+		err = out.Close()
 	}
+	if err != nil {
+		exitWithError(err)
+	}
+	printSummary(converted)
 }
 
-func displayUsageInfo() {
-	fmt.Println("Spice2JSON " + VERSION)
-	fmt.Println("Please provide a valid input schema and a path to the output json")
-	fmt.Println("")
-	fmt.Println("Read from file: spice2json test_schema.zaml [output.json]")
-	fmt.Println("Read from stdin: spice2json -s")
-	fmt.Println("Read from spicedb rest client: spice2json -h http://localhost:8443")
-	fmt.Println("Read from spicedb grpc client: spice2json -g [-insecure] localhost:50051")
-	flag.Usage()
+// writeStreamed converts the schema with the From writers of spice2json, which write each
+// definition as soon as it is mapped, into the split output directory or as ndjson
+func writeStreamed(source string, schema string, namespace string, split bool, outputFileName string, opts spice2json.Options, finalNewline bool, allowEmpty bool) {
+	compiled, err := spice2json.Compile(source, schema, namespace)
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(compiled.ObjectDefinitions) == 0 && !allowEmpty {
+		exitWithError(fmt.Errorf("schema %s has no object definitions, use -allow-empty to allow it", source))
+	}
+
+	var summary spice2json.Stats
+	opts.OnDefinition = func(def *spice2json.Definition) {
+		summary.Definitions++
+		summary.Relations += len(def.Relations)
+		summary.Permissions += len(def.Permissions)
+	}
+	if !opts.NoCaveats {
+		summary.Caveats = len(compiled.CaveatDefinitions)
+	}
+	opts.Source = schema
+
+	if split {
+		_, err = spice2json.WriteSplitFrom(compiled, outputFileName, opts)
+	} else {
+		out := withFinalNewline(createOutput(outputFileName), finalNewline)
+		err = spice2json.WriteNDJSONFrom(compiled, out, opts)
+		if err == nil {
+			err = out.Close()
+		}
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+	printStats(&summary)
 }
 
-// PrettyString https://gosamples.dev/pretty-print-json/
-func PrettyString(str string) (string, error) {
-	var prettyJSON bytes.Buffer
-	if err := json.Indent(&prettyJSON, []byte(str), "", "  "); err != nil {
-		return "", err
+// createOutput opens the output file, or stdout when no file or - is given
+func createOutput(outputFileName string) io.WriteCloser {
+	if outputFileName == "" || outputFileName == "-" {
+		return nopCloser{os.Stdout}
+	}
+	file, err := os.Create(outputFileName)
+	if err != nil {
+		exitWithError(err)
 	}
-	return prettyJSON.String(), nil
+	return file
+}
+
+// nopCloser keeps stdout open after writing
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
 }
 
-// WriteSchemaTo Portions of this code were pulled from https://github.com/oviva-ag/spicedb
-func WriteSchemaTo(schema *compiler.CompiledSchema, w io.Writer) error {
-	var definitions []*Definition
-	for _, def := range schema.ObjectDefinitions {
-		o, err := mapDefinition(def)
+// finalNewline ends the output with a newline on close, unless it is empty or already ends with one
+type finalNewline struct {
+	io.WriteCloser
+	last byte
+}
+
+func withFinalNewline(out io.WriteCloser, enabled bool) io.WriteCloser {
+	if !enabled {
+		return out
+	}
+	return &finalNewline{WriteCloser: out}
+}
+
+func (f *finalNewline) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		f.last = p[len(p)-1]
+	}
+	return f.WriteCloser.Write(p)
+}
+
+func (f *finalNewline) Close() error {
+	if f.last != 0 && f.last != '\n' {
+		if _, err := f.WriteCloser.Write([]byte("\n")); err != nil {
+			f.WriteCloser.Close()
+			return err
+		}
+	}
+	return f.WriteCloser.Close()
+}
+
+// writeOutput writes to the output file, or stdout when no file or - is given
+func writeOutput(output string, outputFileName string) {
+	if outputFileName != "" && outputFileName != "-" {
+		data := []byte(output)
+		err := os.WriteFile(outputFileName, data, 0644)
 		if err != nil {
-			return fmt.Errorf("failed to export %q: %w", def.Name, err)
+			exitWithError(err)
+		}
+	} else {
+		fmt.Print(output)
+	}
+}
+
+// diffSchemas converts both schema files and lists the changes from the old to the new schema,
+// as json or one line per change with the text format
+func diffSchemas(oldPath string, newPath string, namespace string, opts spice2json.Options, format string) (string, error) {
+	var schemas []*spice2json.Schema
+	for _, path := range []string{oldPath, newPath} {
+		schema, source := readSchemaFromPath(path, namespace)
+		converted, err := spice2json.ConvertFrom(source, schema, namespace, opts)
+		if err != nil {
+			return "", err
+		}
+		schemas = append(schemas, converted)
+	}
+
+	changes := spice2json.Diff(schemas[0], schemas[1])
+	switch format {
+	case "json":
+		if changes == nil {
+			changes = []spice2json.Change{}
 		}
-		definitions = append(definitions, o)
+		data, err := json.MarshalIndent(changes, "", "  ")
+		return string(data) + "\n", err
+	case "text":
+		var b strings.Builder
+		for _, change := range changes {
+			b.WriteString(change.String() + "\n")
+		}
+		return b.String(), nil
 	}
+	return "", fmt.Errorf("unknown diff format %q, use json or text", format)
+}
 
-	var caveats []*Caveat
-	for _, caveat := range schema.CaveatDefinitions {
-		o := mapCaveat(caveat)
-		caveats = append(caveats, o)
+// reverseSchema converts spice2json json output back into schema dsl
+func reverseSchema(input string) string {
+	var schema spice2json.Schema
+	err := json.Unmarshal([]byte(input), &schema)
+	if err != nil {
+		exitWithError(err)
 	}
 
-	data, err := json.Marshal(&Schema{
-		Definitions: definitions,
-		Caveats:     caveats,
-	})
+	var buf strings.Builder
+	err = spice2json.WriteDSL(&schema, &buf)
 	if err != nil {
-		return fmt.Errorf("unable to serialize schema for export: %w", err)
+		exitWithError(err)
 	}
+	return buf.String()
+}
 
-	if _, err := w.Write(data); err != nil {
-		return fmt.Errorf("unable to write schema for export: %w", err)
+// spicedbVersion is the version of the spicedb module the schema compiler comes from
+func spicedbVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/authzed/spicedb" {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
 	}
+	return "unknown"
+}
+
+// stringList collects the values of a repeated flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
 	return nil
 }
+
+func displayUsageInfo() {
+	fmt.Println("Spice2JSON " + VERSION)
+	fmt.Println("Please provide a valid input schema and a path to the output json")
+	fmt.Println("")
+	fmt.Println("Read from file: spice2json test_schema.zaml [output.json]")
+	fmt.Println("Read all .zed files in a directory: spice2json schemas/ [output.json]")
+	fmt.Println("Read all files matching a pattern: spice2json 'schemas/*.zed' [output.json]")
+	fmt.Println("Read from stdin: spice2json -s, or spice2json - [output.json]")
+	fmt.Println("Read from spicedb rest client: spice2json -h http://localhost:8443")
+	fmt.Println("Read from spicedb grpc client: spice2json -g [-insecure] localhost:50051")
+	fmt.Println("Read from spicedb grpc client: spice2json -endpoint localhost:50051 -token MyPreSharedKey [-insecure]")
+	fmt.Println("Write one json file per definition: spice2json -split test_schema.zaml output_dir")
+	fmt.Println("Compare two schemas: spice2json -diff [-format text] old.zed new.zed")
+	fmt.Println("Convert json output back to schema dsl: spice2json -reverse output.json [schema.zed]")
+	fmt.Println("")
+	fmt.Println("Output format is json unless -format yaml, toml, dot, mermaid, plantuml or csv is given.")
+	fmt.Println("Output is written to the -o path if given, otherwise to the second argument,")
+	fmt.Println("otherwise to stdout. Use -o - to force stdout.")
+	flag.Usage()
+}
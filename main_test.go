@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alsbury/spice2json/pkg/spice2json"
+)
+
+// binary is spice2json built once by TestMain, the tests run it like a user would
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "spice2json")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binary = filepath.Join(dir, "spice2json")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		os.RemoveAll(dir)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// run runs spice2json with the arguments and returns its stdout, stderr and exit code
+func run(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), stderr.String(), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), 0
+}
+
+// writeFile writes a file into a temporary directory and returns its path
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExitCodes(t *testing.T) {
+	simple := "example/simple.zaml"
+	broken := writeFile(t, "broken.zed", "definition user {\n")
+	dangling := writeFile(t, "dangling.zed", "definition user {}\ndefinition doc {\n\trelation viewer: user\n\tpermission view = viewer->nope\n}\n")
+	invalidJSON := writeFile(t, "invalid.json", "{")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"success", []string{simple}, 0},
+		{"help", []string{"-help"}, 0},
+		{"unknown flag", []string{"-bogus", simple}, exitUsage},
+		{"unknown format", []string{"-format", "nope", simple}, exitUsage},
+		{"invalid indent", []string{"-indent", "x", simple}, exitUsage},
+		{"unknown diff format", []string{"-diff", "-format", "nope", simple, simple}, exitUsage},
+		{"batch of a file", []string{"-batch", simple, t.TempDir()}, exitUsage},
+		{"unknown lint check", []string{"-lint", "-checks", "nope", simple}, exitUsage},
+		{"missing file", []string{filepath.Join(t.TempDir(), "missing.zed")}, exitIO},
+		{"compile error", []string{broken}, exitCompile},
+		{"invalid reverse input", []string{"-reverse", invalidJSON}, exitConversion},
+		{"lint warnings with -Werror", []string{"-lint", "-Werror", dangling}, exitLint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, code := run(t, tt.args...); code != tt.code {
+				t.Errorf("spice2json %v exited with %d, want %d", tt.args, code, tt.code)
+			}
+		})
+	}
+}
+
+func TestExitCodeKeepsOutputFile(t *testing.T) {
+	output := writeFile(t, "keep.json", "keep\n")
+	for _, args := range [][]string{{"-format", "nope"}, {"-indent", "x"}} {
+		if _, _, code := run(t, append(args, "-o", output, "example/simple.zaml")...); code != exitUsage {
+			t.Errorf("spice2json %v exited with %d, want %d", args, code, exitUsage)
+		}
+		if data, _ := os.ReadFile(output); string(data) != "keep\n" {
+			t.Errorf("spice2json %v changed the output file to %q", args, data)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	broken := writeFile(t, "broken.zed", "definition user {}\n\ndefinition document {\n\trelation viewer user\n}\n")
+	stdout, stderr, code := run(t, broken)
+	if code == 0 {
+		t.Fatal("a broken schema exited with 0")
+	}
+	if strings.Contains(stdout, "definitions") {
+		t.Errorf("a broken schema wrote output %q", stdout)
+	}
+	if !strings.Contains(stderr, broken) || !strings.Contains(stderr, "line 4") {
+		t.Errorf("error %q doesn't name the file %s and line 4", stderr, broken)
+	}
+}
+
+func TestDirectoryInput(t *testing.T) {
+	// example/multi has users.zed and docs/document.zed, which uses the definitions of users.zed
+	stdout, stderr, code := run(t, "example/multi")
+	if code != 0 {
+		t.Fatalf("exited with %d: %s", code, stderr)
+	}
+	var schema spice2json.Schema
+	if err := json.Unmarshal([]byte(stdout), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, def := range schema.Definitions {
+		names = append(names, def.Name)
+	}
+	if strings.Join(names, ",") != "document,user,group" {
+		t.Errorf("got definitions %v, want document, user and group in sorted path order", names)
+	}
+
+	viewer := schema.Definitions[0].Relations[1]
+	var types []string
+	for _, rt := range viewer.Types {
+		types = append(types, rt.Type+"#"+rt.Relation)
+	}
+	if strings.Join(types, ",") != "user#,group#member" {
+		t.Errorf("document#viewer has types %v, want user and group#member from users.zed", types)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	in := t.TempDir()
+	files := map[string]string{
+		"users.zed":          "definition user {}\n",
+		"docs/document.zed":  "definition user {}\ndefinition document {\n\trelation viewer: user\n}\n",
+		"billing/broken.zed": "definition account {\n",
+		"billing/plan.zed":   "definition plan {}\n",
+		"auth/broken.zed":    "definition session {\n\trelation owner user\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(in, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := t.TempDir()
+	_, stderr, code := run(t, "-batch", in, out)
+	if code != exitCompile {
+		t.Errorf("exited with %d, want %d", code, exitCompile)
+	}
+	for _, broken := range []string{"billing/broken.zed", "auth/broken.zed"} {
+		if !strings.Contains(stderr, filepath.FromSlash(broken)) {
+			t.Errorf("errors %q don't report %s", stderr, broken)
+		}
+	}
+
+	want := map[string]string{
+		"users.json":         "user",
+		"docs/document.json": "user,document",
+		"billing/plan.json":  "plan",
+	}
+	for name, definitions := range want {
+		data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("the other files are still converted: %v", err)
+			continue
+		}
+		var schema spice2json.Schema
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, def := range schema.Definitions {
+			names = append(names, def.Name)
+		}
+		if strings.Join(names, ",") != definitions {
+			t.Errorf("%s has definitions %v, want %s", name, names, definitions)
+		}
+	}
+	for _, broken := range []string{"billing/broken.json", "auth/broken.json"} {
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(broken))); err == nil {
+			t.Errorf("%s was written for a broken file", broken)
+		}
+	}
+}
+
+func TestParseNamespaceMapping(t *testing.T) {
+	tests := []struct {
+		value string
+		want  namespaceMapping
+		err   bool
+	}{
+		{"", namespaceMapping{}, false},
+		{"app", namespaceMapping{Default: "app"}, false},
+		{"billing/*.zed=billing, auth=auth,shared", namespaceMapping{
+			Default: "shared",
+			Rules:   []namespaceRule{{"billing/*.zed", "billing"}, {"auth", "auth"}},
+		}, false},
+		{"auth/=auth", namespaceMapping{Rules: []namespaceRule{{"auth", "auth"}}}, false},
+		{"legacy=", namespaceMapping{Rules: []namespaceRule{{"legacy", ""}}}, false},
+		{"=billing", namespaceMapping{}, true},
+		{"billing=a=b", namespaceMapping{}, true},
+		{"[billing=billing", namespaceMapping{}, true},
+		{"billing=billing,billing=other", namespaceMapping{}, true},
+		{"auth=auth,auth/=other", namespaceMapping{}, true},
+		{"app,billing=billing,other", namespaceMapping{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseNamespaceMapping(tt.value)
+		if tt.err {
+			if err == nil {
+				t.Errorf("-n %q gave %+v, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("-n %q: %v", tt.value, err)
+			continue
+		}
+		if got.Default != tt.want.Default || !slices.Equal(got.Rules, tt.want.Rules) {
+			t.Errorf("-n %q gave %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNamespaceMapping(t *testing.T) {
+	mapping, err := parseNamespaceMapping("billing/*.zed=billing,auth=auth,auth/legacy=legacy,auth/legacy/*.zed=old,shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"schemas/billing/invoice.zed", "billing"},
+		{"schemas/billing/archive/invoice.zed", "shared"},
+		{"schemas/auth/session.zed", "auth"},
+		{"schemas/auth/oidc/provider.zed", "auth"},
+		{"schemas/auth/legacy/token/key.zed", "legacy"},
+		{"schemas/auth/legacy/token.zed", "old"},
+		{"schemas/authz.zed", "shared"},
+		{"schemas/users.zed", "shared"},
+		{"elsewhere/auth/session.zed", "shared"},
+	}
+	for _, tt := range tests {
+		if got := mapping.namespace("schemas", filepath.FromSlash(tt.file)); got != tt.want {
+			t.Errorf("%s has namespace %q, want %q", tt.file, got, tt.want)
+		}
+	}
+
+	if got := (namespaceMapping{}).namespace("schemas", "schemas/users.zed"); got != "" {
+		t.Errorf("without -n got namespace %q, want none", got)
+	}
+}
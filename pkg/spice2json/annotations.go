@@ -0,0 +1,52 @@
+package spice2json
+
+import (
+	"regexp"
+	"strings"
+
+	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// annotationRegex matches comment lines like "@owner: platform-team" or "@sensitive"
+var annotationRegex = regexp.MustCompile(`^@([A-Za-z0-9_.-]+)(?::\s*(.*?))?\s*$`)
+
+// mapComment is getMetadataComments with the annotations taken out of the comment when
+// Options.ParseAnnotations is set
+func mapComment(metadata *corev1.Metadata, opts Options) (string, map[string]string) {
+	comment := getMetadataComments(metadata, opts)
+	if !opts.ParseAnnotations {
+		return comment, nil
+	}
+	return parseAnnotations(comment)
+}
+
+// parseAnnotations splits the annotation lines from the prose of the comment, a flag without
+// a value is set to "true"
+func parseAnnotations(comment string) (string, map[string]string) {
+	var annotations map[string]string
+	var prose []string
+	for _, line := range strings.Split(comment, "\n") {
+		match := annotationRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			prose = append(prose, line)
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		value := match[2]
+		if !strings.Contains(match[0], ":") {
+			value = "true"
+		}
+		annotations[match[1]] = value
+	}
+	return strings.Trim(strings.Join(prose, "\n"), "\n"), annotations
+}
+
+// isExcluded reports whether the doc comment has an @exclude line, also when comments are
+// left out or annotations aren't parsed
+func isExcluded(metadata *corev1.Metadata) bool {
+	_, annotations := parseAnnotations(getMetadataComments(metadata, Options{}))
+	_, ok := annotations["exclude"]
+	return ok
+}
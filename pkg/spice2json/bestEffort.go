@@ -0,0 +1,125 @@
+package spice2json
+
+import (
+	"strings"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/schemadsl/lexer"
+)
+
+// ConvertBestEffort is ConvertFrom that doesn't give up on the first error. When the schema
+// doesn't compile as a whole each definition and caveat is compiled on its own, the ones that
+// fail are skipped with a warning. Subject types and caveats that are not declared in the
+// schema are reported as warnings as well.
+func ConvertBestEffort(sourceName string, schemaSource string, defaultNamespace string, opts Options) (*Schema, []Warning, error) {
+	var warnings []Warning
+	def, err := Compile(sourceName, schemaSource, defaultNamespace)
+	if err != nil {
+		def = &compiler.CompiledSchema{}
+		for _, block := range declarationBlocks(schemaSource) {
+			compiled, err := Compile(sourceName, block, defaultNamespace)
+			if err != nil {
+				warnings = append(warnings, Warning{Check: "compile", Location: sourceName, Message: "skipped, " + err.Error()})
+				continue
+			}
+			def.ObjectDefinitions = append(def.ObjectDefinitions, compiled.ObjectDefinitions...)
+			def.CaveatDefinitions = append(def.CaveatDefinitions, compiled.CaveatDefinitions...)
+		}
+	}
+
+	opts.Source = schemaSource
+	schema, err := MapSchema(def, opts)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	return schema, append(warnings, unresolvedReferences(schema)...), nil
+}
+
+// declarationBlocks splits the schema source into its top level definitions and caveats, each
+// with its leading comments. Everything outside a block is blanked out, keeping the line breaks,
+// so positions in compiler errors still match the whole source.
+func declarationBlocks(source string) []string {
+	lex := lexer.NewPeekableLexer(lexer.Lex(input.Source("schema"), source))
+	defer lex.Close()
+
+	var blocks []string
+	addBlock := func(start int, end int) {
+		if strings.TrimSpace(source[start:end]) == "" {
+			return
+		}
+		blanked := []byte(source)
+		for i := range blanked {
+			if (i < start || i >= end) && blanked[i] != '\n' {
+				blanked[i] = ' '
+			}
+		}
+		blocks = append(blocks, string(blanked))
+	}
+
+	start := -1
+	depth := 0
+	for {
+		token := lex.NextToken()
+		position := int(token.Position)
+		switch token.Kind {
+		case lexer.TokenTypeEOF, lexer.TokenTypeError:
+			if start >= 0 {
+				addBlock(start, len(source))
+			}
+			return blocks
+		case lexer.TokenTypeWhitespace, lexer.TokenTypeNewline, lexer.TokenTypeSyntheticSemicolon:
+		case lexer.TokenTypeLeftBrace:
+			depth++
+		case lexer.TokenTypeRightBrace:
+			depth--
+			if depth == 0 && start >= 0 {
+				addBlock(start, position+len(token.Value))
+				start = -1
+			}
+		default:
+			if start < 0 {
+				start = position
+			}
+		}
+	}
+}
+
+// unresolvedReferences reports subject types and caveats of relations that are not declared
+// in the schema
+func unresolvedReferences(schema *Schema) []Warning {
+	declared := map[string]bool{}
+	for _, def := range schema.Definitions {
+		declared[qualifiedName(def.Name, def.Namespace)] = true
+	}
+	caveats := map[string]bool{}
+	for _, caveat := range schema.Caveats {
+		caveats[caveat.Name] = true
+	}
+
+	var warnings []Warning
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, r := range def.Relations {
+			for _, t := range r.Types {
+				subject := qualifiedName(t.Type, t.Namespace)
+				if !declared[subject] {
+					warnings = append(warnings, Warning{
+						Check:    "unresolved",
+						Location: defName + "#" + r.Name,
+						Message:  "subject type " + subject + " is not defined",
+					})
+				}
+				if t.Caveat != "" && !caveats[t.Caveat] {
+					warnings = append(warnings, Warning{
+						Check:    "unresolved",
+						Location: defName + "#" + r.Name,
+						Message:  "caveat " + t.Caveat + " is not defined",
+					})
+				}
+			}
+		}
+	}
+	return warnings
+}
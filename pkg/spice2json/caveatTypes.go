@@ -0,0 +1,51 @@
+package spice2json
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// caveatTypeGenerics are the caveat parameter types with the number of their type arguments,
+// the stable set of base types used in normalized parameter types
+var caveatTypeGenerics = map[string]int{
+	"any":       0,
+	"bool":      0,
+	"string":    0,
+	"int":       0,
+	"uint":      0,
+	"double":    0,
+	"bytes":     0,
+	"duration":  0,
+	"timestamp": 0,
+	"ipaddress": 0,
+	"list":      1,
+	"map":       1,
+}
+
+// normalizeCaveatType returns the parameter type with its type arguments, e.g. list<string>
+// or map<list<int>>, and errors on types outside caveatTypeGenerics
+func normalizeCaveatType(t *corev1.CaveatTypeReference) (string, error) {
+	name := strings.ToLower(t.GetTypeName())
+	generics, ok := caveatTypeGenerics[name]
+	if !ok {
+		return "", fmt.Errorf("unknown caveat parameter type %q", t.GetTypeName())
+	}
+	if len(t.GetChildTypes()) != generics {
+		return "", fmt.Errorf("caveat parameter type %s expects %d type arguments, got %d", name, generics, len(t.GetChildTypes()))
+	}
+	if generics == 0 {
+		return name, nil
+	}
+
+	children := make([]string, len(t.GetChildTypes()))
+	for i, child := range t.GetChildTypes() {
+		normalized, err := normalizeCaveatType(child)
+		if err != nil {
+			return "", err
+		}
+		children[i] = normalized
+	}
+	return name + "<" + strings.Join(children, ", ") + ">", nil
+}
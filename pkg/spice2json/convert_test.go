@@ -0,0 +1,207 @@
+package spice2json
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenFormats are the output formats compared with a golden file, testdata/<input>.<format>
+var goldenFormats = []string{"json", "toml", "mermaid"}
+
+// TestConvert converts each testdata/*.zed with the default options and compares the output
+// in each golden format, and rendered with testdata/markdown.tmpl as .md, with the golden
+// file next to it, run with -update to rewrite them
+func TestConvert(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no testdata/*.zed inputs")
+	}
+	markdown, err := os.ReadFile("testdata/markdown.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".zed")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schema, err := ConvertFrom(input, string(source), "", Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, format := range goldenFormats {
+				var got bytes.Buffer
+				if err := WriteAs(schema, format, &got, Options{Indent: "  "}); err != nil {
+					t.Fatal(err)
+				}
+				compareGolden(t, strings.TrimSuffix(input, ".zed")+"."+format, got.Bytes())
+			}
+
+			var got bytes.Buffer
+			if err := WriteTemplate(schema, &got, "markdown.tmpl", string(markdown)); err != nil {
+				t.Fatal(err)
+			}
+			compareGolden(t, strings.TrimSuffix(input, ".zed")+".md", got.Bytes())
+		})
+	}
+}
+
+// compareGolden compares the output with the golden file, or rewrites it with -update. The
+// output ends with a newline like the golden files.
+func compareGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	if !bytes.HasSuffix(got, []byte("\n")) {
+		got = append(got, '\n')
+	}
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("output differs from %s, run go test -update if the change is intended\ngot:\n%s", golden, got)
+	}
+}
+
+func TestRespectExclude(t *testing.T) {
+	source, err := os.ReadFile("testdata/exclude.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		respectExclude bool
+		want           string
+	}{
+		{false, "user,experiment,internal,document"},
+		{true, "user,document"},
+	}
+	for _, tt := range tests {
+		opts := Options{RespectExclude: tt.respectExclude}
+		schema, err := ConvertFrom("testdata/exclude.zed", string(source), "", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, def := range schema.Definitions {
+			names = append(names, def.Name)
+		}
+		if strings.Join(names, ",") != tt.want {
+			t.Errorf("RespectExclude %v got definitions %v, want %s", tt.respectExclude, names, tt.want)
+		}
+
+		compiled, err := Compile("testdata/exclude.zed", string(source), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = nil
+		err = StreamDefinitions(compiled, opts, func(def *Definition) error {
+			names = append(names, def.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(names, ",") != tt.want {
+			t.Errorf("RespectExclude %v streamed definitions %v, want %s", tt.respectExclude, names, tt.want)
+		}
+	}
+}
+
+// TestCanonicalDSL renders each testdata/*.zed with the zed format, compiles the output again
+// and expects the json of the original conversion with its definitions and caveats sorted
+func TestCanonicalDSL(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			source, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schema, err := Convert(string(source), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dsl bytes.Buffer
+			if err := WriteAs(schema, "zed", &dsl, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			again, err := Convert(dsl.String(), "")
+			if err != nil {
+				t.Fatalf("canonical schema doesn't compile: %v\n%s", err, dsl.String())
+			}
+
+			sortDefinitions(schema.Definitions)
+			sortCaveats(schema.Caveats)
+			var want, got bytes.Buffer
+			if err := WriteSchemaIndentTo(schema, &want, "json", "  "); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteSchemaIndentTo(again, &got, "json", "  "); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("compiling the canonical schema\n%s\nchanged the json to\n%s", dsl.String(), got.String())
+			}
+
+			var twice bytes.Buffer
+			if err := WriteAs(again, "zed", &twice, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if twice.String() != dsl.String() {
+				t.Errorf("the canonical schema isn't stable, rendered again it is\n%s", twice.String())
+			}
+		})
+	}
+}
+
+// FuzzConvert compiles mutated schemas and writes each registered format, the schema may fail
+// to compile or convert but nothing may panic. The corpus is seeded with testdata/*.zed and
+// example/*.zed.
+func FuzzConvert(f *testing.F) {
+	inputs, err := filepath.Glob("testdata/*.zed")
+	if err != nil {
+		f.Fatal(err)
+	}
+	examples, err := filepath.Glob("../../example/*.zed")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, input := range append(inputs, examples...) {
+		source, err := os.ReadFile(input)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(source))
+	}
+
+	f.Fuzz(func(t *testing.T, source string) {
+		schema, err := ConvertFrom("fuzz", source, "", Options{FaithfulTree: true, ParseAnnotations: true})
+		if err != nil {
+			return
+		}
+		for _, format := range Formats() {
+			_ = WriteAs(schema, format, io.Discard, Options{Indent: "  "})
+		}
+	})
+}
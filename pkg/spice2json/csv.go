@@ -0,0 +1,30 @@
+package spice2json
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// writeCSV flattens the relation types into one row per definition, relation and allowed
+// subject, with * as subject relation for wildcards
+func writeCSV(schema *Schema) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"definition", "relation", "subject_type", "subject_relation", "caveat"}}
+	for _, def := range schema.Definitions {
+		name := qualifiedName(def.Name, def.Namespace)
+		for _, r := range def.Relations {
+			for _, t := range r.Types {
+				relation := t.Relation
+				if t.Wildcard {
+					relation = "*"
+				}
+				rows = append(rows, []string{name, r.Name, qualifiedName(t.Type, t.Namespace), relation, t.Caveat})
+			}
+		}
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,201 @@
+package spice2json
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change is a difference between two schemas, located at a definition, definition#member or
+// caveat name. Old and New hold the relation types, permission expression or caveat parameters
+// and expression,
+// they are empty for definitions.
+type Change struct {
+	Change   string `json:"change"`
+	Kind     string `json:"kind"`
+	Location string `json:"location"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Change == "changed":
+		return fmt.Sprintf("~ %s %s: %s => %s", c.Kind, c.Location, c.Old, c.New)
+	case c.Old == "" && c.New == "":
+		return fmt.Sprintf("%s %s %s", diffSymbols[c.Change], c.Kind, c.Location)
+	}
+	return fmt.Sprintf("%s %s %s: %s", diffSymbols[c.Change], c.Kind, c.Location, c.Old+c.New)
+}
+
+var diffSymbols = map[string]string{
+	"added":   "+",
+	"removed": "-",
+}
+
+// Diff compares two schemas by definition, relation, permission and caveat name. Permissions
+// are compared semantically, the order of union and intersection children doesn't matter.
+// Comments and source positions are ignored.
+func Diff(old *Schema, new *Schema) []Change {
+	var changes []Change
+	add := func(change string, kind string, location string, o string, n string) {
+		changes = append(changes, Change{Change: change, Kind: kind, Location: location, Old: o, New: n})
+	}
+
+	oldDefinitions := map[string]*Definition{}
+	for _, def := range old.Definitions {
+		oldDefinitions[qualifiedName(def.Name, def.Namespace)] = def
+	}
+	newDefinitions := map[string]*Definition{}
+	for _, def := range new.Definitions {
+		newDefinitions[qualifiedName(def.Name, def.Namespace)] = def
+	}
+
+	for _, def := range old.Definitions {
+		name := qualifiedName(def.Name, def.Namespace)
+		if _, ok := newDefinitions[name]; !ok {
+			add("removed", "definition", name, "", "")
+		}
+	}
+	for _, def := range new.Definitions {
+		name := qualifiedName(def.Name, def.Namespace)
+		oldDef, ok := oldDefinitions[name]
+		if !ok {
+			add("added", "definition", name, "", "")
+			continue
+		}
+
+		oldRelations := map[string]string{}
+		for _, r := range oldDef.Relations {
+			oldRelations[r.Name] = relationTypesKey(r)
+		}
+		newRelations := map[string]string{}
+		for _, r := range def.Relations {
+			newRelations[r.Name] = relationTypesKey(r)
+		}
+		for _, r := range oldDef.Relations {
+			if _, ok := newRelations[r.Name]; !ok {
+				add("removed", "relation", name+"#"+r.Name, oldRelations[r.Name], "")
+			}
+		}
+		for _, r := range def.Relations {
+			oldTypes, ok := oldRelations[r.Name]
+			switch {
+			case !ok:
+				add("added", "relation", name+"#"+r.Name, "", newRelations[r.Name])
+			case oldTypes != newRelations[r.Name]:
+				add("changed", "relation", name+"#"+r.Name, oldTypes, newRelations[r.Name])
+			}
+		}
+
+		oldPermissions := map[string]*Permission{}
+		for _, p := range oldDef.Permissions {
+			oldPermissions[p.Name] = p
+		}
+		newPermissions := map[string]bool{}
+		for _, p := range def.Permissions {
+			newPermissions[p.Name] = true
+		}
+		for _, p := range oldDef.Permissions {
+			if !newPermissions[p.Name] {
+				add("removed", "permission", name+"#"+p.Name, userSetExpression(p.UserSet), "")
+			}
+		}
+		for _, p := range def.Permissions {
+			oldPermission, ok := oldPermissions[p.Name]
+			switch {
+			case !ok:
+				add("added", "permission", name+"#"+p.Name, "", userSetExpression(p.UserSet))
+			case userSetKey(oldPermission.UserSet) != userSetKey(p.UserSet):
+				add("changed", "permission", name+"#"+p.Name, userSetExpression(oldPermission.UserSet), userSetExpression(p.UserSet))
+			}
+		}
+	}
+
+	oldCaveats := map[string]string{}
+	for _, caveat := range old.Caveats {
+		oldCaveats[caveat.Name] = caveatKey(caveat)
+	}
+	newCaveats := map[string]string{}
+	for _, caveat := range new.Caveats {
+		newCaveats[caveat.Name] = caveatKey(caveat)
+	}
+	for _, caveat := range old.Caveats {
+		if _, ok := newCaveats[caveat.Name]; !ok {
+			add("removed", "caveat", caveat.Name, oldCaveats[caveat.Name], "")
+		}
+	}
+	for _, caveat := range new.Caveats {
+		oldCaveat, ok := oldCaveats[caveat.Name]
+		switch {
+		case !ok:
+			add("added", "caveat", caveat.Name, "", newCaveats[caveat.Name])
+		case oldCaveat != newCaveats[caveat.Name]:
+			add("changed", "caveat", caveat.Name, oldCaveat, newCaveats[caveat.Name])
+		}
+	}
+
+	return changes
+}
+
+// relationTypesKey is the sorted DSL form of the relation types
+func relationTypesKey(r *Relation) string {
+	types := make([]string, len(r.Types))
+	for i, t := range r.Types {
+		types[i] = relationTypeDSL(t)
+	}
+	sort.Strings(types)
+	return strings.Join(types, " | ")
+}
+
+// caveatKey is the sorted parameter list of the caveat, with the normalized types when they
+// are known, followed by its expression
+func caveatKey(caveat *Caveat) string {
+	parameters := make([]string, 0, len(caveat.Parameters))
+	for _, name := range sortedParameterNames(caveat.Parameters) {
+		typeName := caveat.Parameters[name]
+		if normalized := caveat.ParameterTypes[name]; normalized != "" {
+			typeName = normalized
+		}
+		parameters = append(parameters, name+" "+typeName)
+	}
+	key := strings.Join(parameters, ", ")
+	if caveat.Expression != "" {
+		key += " { " + caveat.Expression + " }"
+	}
+	return key
+}
+
+// userSetKey is a canonical form of the user set, nested unions and intersections are flattened
+// and their children sorted, exclusion keeps its order
+func userSetKey(set *UserSet) string {
+	if set == nil {
+		return "nil"
+	}
+	if set.Operation == "" {
+		return userSetExpression(set)
+	}
+	if len(set.Children) == 0 {
+		return "nil"
+	}
+	if len(set.Children) == 1 {
+		return userSetKey(set.Children[0])
+	}
+
+	var keys []string
+	var collect func(children []*UserSet)
+	collect = func(children []*UserSet) {
+		for _, child := range children {
+			if set.Operation != "exclusion" && child != nil && child.Operation == set.Operation {
+				collect(child.Children)
+				continue
+			}
+			keys = append(keys, userSetKey(child))
+		}
+	}
+	collect(set.Children)
+	if set.Operation != "exclusion" {
+		sort.Strings(keys)
+	}
+	return set.Operation + "(" + strings.Join(keys, ", ") + ")"
+}
@@ -0,0 +1,61 @@
+package spice2json
+
+import (
+	"fmt"
+	"strings"
+)
+
+var dotShapes = map[string]string{
+	"definition": "box",
+	"relation":   "ellipse",
+	"permission": "octagon",
+}
+
+// writeDot renders the permission graph as Graphviz DOT, with one cluster per definition
+func writeDot(schema *Schema) []byte {
+	g := buildGraph(schema)
+
+	var b strings.Builder
+	b.WriteString("digraph schema {\n")
+	b.WriteString("  rankdir=LR;\n")
+
+	for _, def := range g.Definitions {
+		fmt.Fprintf(&b, "  subgraph %q {\n", "cluster_"+def)
+		fmt.Fprintf(&b, "    label=%q;\n", def)
+		for _, n := range g.Nodes {
+			if n.Definition == def {
+				fmt.Fprintf(&b, "    %q [label=%q, shape=%s];\n", n.ID, n.Label, dotShapes[n.Kind])
+			}
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, e := range g.Edges {
+		var attrs []string
+		label := e.Label
+		if e.Kind == "arrow" {
+			label = e.Label + "->"
+		}
+		if e.Wildcard {
+			label = "*"
+		}
+		if e.Caveat != "" {
+			label = strings.TrimSpace(label + " with " + e.Caveat)
+		}
+		if label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", label))
+		}
+		if e.Kind != "subject" {
+			attrs = append(attrs, "style=dashed")
+		}
+
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, "  %q -> %q [%s];\n", e.From, e.To, strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+		}
+	}
+
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,194 @@
+package spice2json
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var operationPrecedence = map[string]int{
+	"exclusion":    1,
+	"intersection": 2,
+	"union":        3,
+}
+
+var operationSymbols = map[string]string{
+	"exclusion":    " - ",
+	"intersection": " & ",
+	"union":        " + ",
+}
+
+// leafPrecedence is higher than any operation, relations and arrows never need parentheses
+const leafPrecedence = 4
+
+func userSetPrecedence(set *UserSet) int {
+	if set == nil || set.Operation == "" || len(set.Children) == 0 {
+		return leafPrecedence
+	}
+	if len(set.Children) == 1 {
+		return userSetPrecedence(set.Children[0])
+	}
+	return operationPrecedence[set.Operation]
+}
+
+// userSetExpression renders the user set tree as a schema DSL expression, adding parentheses
+// only where the DSL operator precedence (+ binds tighter than &, which binds tighter than -)
+// requires them
+func userSetExpression(set *UserSet) string {
+	if set == nil {
+		return "nil"
+	}
+	if set.Operation == "" {
+		if set.Permission != "" {
+			return set.Relation + "->" + set.Permission
+		}
+		return set.Relation
+	}
+	if len(set.Children) == 0 {
+		return "nil"
+	}
+	if len(set.Children) == 1 {
+		return userSetExpression(set.Children[0])
+	}
+
+	precedence := operationPrecedence[set.Operation]
+	parts := make([]string, len(set.Children))
+	for i, child := range set.Children {
+		expr := userSetExpression(child)
+		childPrecedence := userSetPrecedence(child)
+		// exclusion is left associative so a nested exclusion on the right keeps its parentheses
+		if childPrecedence < precedence || (childPrecedence == precedence && set.Operation == "exclusion" && i > 0) {
+			expr = "(" + expr + ")"
+		}
+		parts[i] = expr
+	}
+	return strings.Join(parts, operationSymbols[set.Operation])
+}
+
+func writeDSLComment(b *strings.Builder, comment string, indent string) {
+	if comment == "" {
+		return
+	}
+	lines := strings.Split(comment, "\n")
+	if len(lines) == 1 {
+		fmt.Fprintf(b, "%s/** %s */\n", indent, comment)
+		return
+	}
+	fmt.Fprintf(b, "%s/**\n", indent)
+	for _, line := range lines {
+		fmt.Fprintf(b, "%s * %s\n", indent, line)
+	}
+	fmt.Fprintf(b, "%s */\n", indent)
+}
+
+func relationTypeDSL(t *RelationType) string {
+	subject := qualifiedName(t.Type, t.Namespace)
+	switch {
+	case t.Wildcard || t.Relation == "*":
+		subject += ":*"
+	case t.Relation != "":
+		subject += "#" + t.Relation
+	}
+	if t.Caveat != "" {
+		subject += " with " + t.Caveat
+	}
+	return subject
+}
+
+// dslMember is a relation or permission written as DSL, with its index in the source
+type dslMember struct {
+	Index int
+	DSL   string
+}
+
+// definitionMembers returns the relations and permissions of the definition in source order.
+// Without indexes, e.g. from older output, all relations come before the permissions.
+func definitionMembers(def *Definition) []dslMember {
+	var members []dslMember
+	for _, r := range def.Relations {
+		var b strings.Builder
+		writeDSLComment(&b, r.Comment, "\t")
+		types := make([]string, len(r.Types))
+		for i, t := range r.Types {
+			types[i] = relationTypeDSL(t)
+		}
+		fmt.Fprintf(&b, "\trelation %s: %s\n", r.Name, strings.Join(types, " | "))
+		members = append(members, dslMember{Index: r.Index, DSL: b.String()})
+	}
+	for _, p := range def.Permissions {
+		var b strings.Builder
+		writeDSLComment(&b, p.Comment, "\t")
+		fmt.Fprintf(&b, "\tpermission %s = %s\n", p.Name, userSetExpression(p.UserSet))
+		members = append(members, dslMember{Index: p.Index, DSL: b.String()})
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Index < members[j].Index
+	})
+	return members
+}
+
+// WriteDSL converts the schema back into SpiceDB schema DSL
+func WriteDSL(schema *Schema, w io.Writer) error {
+	var b strings.Builder
+
+	for i, caveat := range schema.Caveats {
+		if caveat.Expression == "" {
+			return fmt.Errorf("caveat %q can't be converted back to DSL without its expression", caveat.Name)
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writeDSLComment(&b, caveat.Comment, "")
+		order := caveat.ParameterOrder
+		if len(order) != len(caveat.Parameters) {
+			order = sortedParameterNames(caveat.Parameters)
+		}
+		parameters := make([]string, len(order))
+		for i, name := range order {
+			typeName := caveat.Parameters[name]
+			if normalized := caveat.ParameterTypes[name]; normalized != "" {
+				typeName = normalized
+			}
+			if typeName == "list" || typeName == "map" {
+				return fmt.Errorf("caveat %q can't be converted back to DSL, parameter %q is missing the element type of %s", caveat.Name, name, typeName)
+			}
+			parameters[i] = name + " " + typeName
+		}
+		fmt.Fprintf(&b, "caveat %s(%s) {\n\t%s\n}\n", caveat.Name, strings.Join(parameters, ", "), caveat.Expression)
+	}
+
+	for i, def := range schema.Definitions {
+		if i > 0 || len(schema.Caveats) > 0 {
+			b.WriteString("\n")
+		}
+		writeDSLComment(&b, def.Comment, "")
+		name := qualifiedName(def.Name, def.Namespace)
+		if len(def.Relations) == 0 && len(def.Permissions) == 0 {
+			fmt.Fprintf(&b, "definition %s {}\n", name)
+			continue
+		}
+
+		fmt.Fprintf(&b, "definition %s {\n", name)
+		for _, m := range definitionMembers(def) {
+			b.WriteString(m.DSL)
+		}
+		b.WriteString("}\n")
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("unable to write schema dsl: %w", err)
+	}
+	return nil
+}
+
+// writeCanonicalDSL writes the schema as DSL with the definitions and caveats sorted by name,
+// relations and permissions keep their source order. The schema itself is left unsorted.
+func writeCanonicalDSL(schema *Schema, w io.Writer, opts Options) error {
+	canonical := *schema
+	canonical.Definitions = append([]*Definition(nil), schema.Definitions...)
+	canonical.Caveats = append([]*Caveat(nil), schema.Caveats...)
+	sortDefinitions(canonical.Definitions)
+	sortCaveats(canonical.Caveats)
+	return WriteDSL(&canonical, w)
+}
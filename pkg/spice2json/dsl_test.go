@@ -0,0 +1,102 @@
+package spice2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestWriteDSLRoundTrip reads the json of each fixture back like -reverse, writes it as DSL,
+// compiles that again and expects the same json
+func TestWriteDSLRoundTrip(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	examples, err := filepath.Glob("../../example/*.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range append(inputs, examples...) {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			source, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schema, err := Convert(string(source), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want bytes.Buffer
+			if err := WriteSchemaIndentTo(schema, &want, "json", "  "); err != nil {
+				t.Fatal(err)
+			}
+			var decoded Schema
+			if err := json.Unmarshal(want.Bytes(), &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			var dsl strings.Builder
+			if err := WriteDSL(&decoded, &dsl); err != nil {
+				t.Fatal(err)
+			}
+			again, err := Convert(dsl.String(), "")
+			if err != nil {
+				t.Fatalf("regenerated schema doesn't compile: %v\n%s", err, dsl.String())
+			}
+
+			var got bytes.Buffer
+			if err := WriteSchemaIndentTo(again, &got, "json", "  "); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("round trip through\n%s\nchanged the json to\n%s", dsl.String(), got.String())
+			}
+		})
+	}
+}
+
+func TestUserSetExpression(t *testing.T) {
+	// the DSL binds + tighter than &, which binds tighter than -
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"viewer + editor", "viewer + editor"},
+		{"(viewer + editor) & owner", "viewer + editor & owner"},
+		{"viewer + (editor & owner)", "viewer + (editor & owner)"},
+		{"(viewer & editor) + (owner & parent->view)", "(viewer & editor) + (owner & parent->view)"},
+		{"viewer & (editor + owner) & parent->view", "viewer & editor + owner & parent->view"},
+		{"(viewer - editor) & owner", "(viewer - editor) & owner"},
+		{"viewer - (editor - owner)", "viewer - (editor - owner)"},
+		{"(viewer - editor) - owner", "viewer - editor - owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			schema := convertPermission(t, tt.source)
+			permission := schema.Definitions[1].Permissions[0]
+			if permission.Expression != tt.want {
+				t.Fatalf("got %q, want %q", permission.Expression, tt.want)
+			}
+			again := convertPermission(t, permission.Expression)
+			if userSetKey(again.Definitions[1].Permissions[0].UserSet) != userSetKey(permission.UserSet) {
+				t.Errorf("%q compiles to a different user set than %q", permission.Expression, tt.source)
+			}
+		})
+	}
+}
+
+// convertPermission converts a document definition with the expression as permission view
+func convertPermission(t *testing.T, expression string) *Schema {
+	t.Helper()
+	source := "definition user {}\ndefinition document {\n\trelation parent: document\n\trelation viewer: user\n\trelation editor: user\n\trelation owner: user\n\tpermission view = " + expression + "\n}\n"
+	schema, err := Convert(source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
@@ -0,0 +1,81 @@
+package spice2json
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/schemadsl/lexer"
+)
+
+// SourceFile is a named part of a schema, e.g. one of the files read from a directory
+type SourceFile struct {
+	Name   string
+	Source string
+}
+
+// EmbedSource sets Source to the schema DSL, a single file is embedded as a string and
+// multiple files as a map of file name to DSL
+func (s *Schema) EmbedSource(files []SourceFile) {
+	if len(files) == 1 {
+		s.Source = files[0].Source
+		return
+	}
+	sources := make(map[string]string, len(files))
+	for _, file := range files {
+		sources[file.Name] = file.Source
+	}
+	s.Source = sources
+}
+
+// declaredNames scans the schema source and returns the names of its definitions and caveats
+// as written in the source
+func declaredNames(source string) []string {
+	lex := lexer.NewPeekableLexer(lexer.Lex(input.Source("schema"), source))
+	defer lex.Close()
+
+	var names []string
+	var name strings.Builder
+	inName := false
+	for {
+		token := lex.NextToken()
+		switch token.Kind {
+		case lexer.TokenTypeEOF, lexer.TokenTypeError:
+			return names
+		case lexer.TokenTypeKeyword:
+			if token.Value == "definition" || token.Value == "caveat" {
+				inName = true
+				name.Reset()
+			}
+		case lexer.TokenTypeIdentifier, lexer.TokenTypeDiv:
+			if inName {
+				name.WriteString(token.Value)
+			}
+		case lexer.TokenTypeWhitespace, lexer.TokenTypeNewline, lexer.TokenTypeSyntheticSemicolon:
+		default:
+			if inName && name.Len() > 0 {
+				names = append(names, name.String())
+			}
+			inName = false
+		}
+	}
+}
+
+// CheckDuplicateDefinitions returns an error naming both files when a definition or caveat is
+// declared in more than one of the files. Names without a namespace/ prefix are compared with
+// the default namespace applied, as the compiler does.
+func CheckDuplicateDefinitions(files []SourceFile, defaultNamespace string) error {
+	declared := map[string]string{}
+	for _, file := range files {
+		for _, name := range declaredNames(file.Source) {
+			if defaultNamespace != "" && !strings.Contains(name, "/") {
+				name = defaultNamespace + "/" + name
+			}
+			if other, ok := declared[name]; ok && other != file.Name {
+				return fmt.Errorf("duplicate definition %q in files %s and %s", name, other, file.Name)
+			}
+			declared[name] = file.Name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package spice2json
+
+import "io"
+
+type edgeListNode struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type edgeListEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label"`
+}
+
+type edgeList struct {
+	Nodes []edgeListNode `json:"nodes"`
+	Edges []edgeListEdge `json:"edges"`
+}
+
+// writeEdges writes the permission graph as json node and edge lists for graph databases.
+// Edge labels are subject, subject:* for wildcards, reference, arrow:<relation> and caveat
+// from a relation to the caveats its types require.
+func writeEdges(schema *Schema, w io.Writer, opts Options) error {
+	g := buildGraph(schema)
+
+	list := edgeList{Nodes: []edgeListNode{}, Edges: []edgeListEdge{}}
+	for _, n := range g.Nodes {
+		list.Nodes = append(list.Nodes, edgeListNode{ID: n.ID, Type: n.Kind, Name: n.Label})
+	}
+	for _, caveat := range schema.Caveats {
+		list.Nodes = append(list.Nodes, edgeListNode{ID: "caveat:" + caveat.Name, Type: "caveat", Name: caveat.Name})
+	}
+
+	caveatEdges := map[edgeListEdge]bool{}
+	for _, e := range g.Edges {
+		label := e.Kind
+		switch {
+		case e.Kind == "arrow":
+			label = "arrow:" + e.Label
+		case e.Wildcard:
+			label = "subject:*"
+		}
+		list.Edges = append(list.Edges, edgeListEdge{From: e.From, To: e.To, Label: label})
+
+		if e.Caveat != "" {
+			edge := edgeListEdge{From: e.From, To: "caveat:" + e.Caveat, Label: "caveat"}
+			if !caveatEdges[edge] {
+				caveatEdges[edge] = true
+				list.Edges = append(list.Edges, edge)
+			}
+		}
+	}
+	return writeDocument(list, w, "json", opts.Indent)
+}
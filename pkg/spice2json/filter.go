@@ -0,0 +1,31 @@
+package spice2json
+
+import "fmt"
+
+// FilterDefinitions keeps only the definitions matching one of the names, given as name or
+// namespace/name. It returns an error for the first name that matches no definition.
+func (s *Schema) FilterDefinitions(names []string) error {
+	matched := map[string]bool{}
+	var definitions []*Definition
+	for _, def := range s.Definitions {
+		keep := false
+		for _, name := range names {
+			if name == def.Name || name == qualifiedName(def.Name, def.Namespace) {
+				matched[name] = true
+				keep = true
+			}
+		}
+		if keep {
+			definitions = append(definitions, def)
+		}
+	}
+
+	for _, name := range names {
+		if !matched[name] {
+			return fmt.Errorf("no definition named %q", name)
+		}
+	}
+
+	s.Definitions = definitions
+	return nil
+}
@@ -0,0 +1,93 @@
+package spice2json
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Fingerprint is a sha-256 hex digest of the schema that doesn't depend on the declaration
+// order, the order of union and intersection operands, the embedded source, source positions,
+// caveat expression asts or comment whitespace. With ignoreComments comments and their
+// annotations are left out.
+func (s *Schema) Fingerprint(ignoreComments bool) (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	var canonical Schema
+	if err := json.Unmarshal(data, &canonical); err != nil {
+		return "", err
+	}
+	canonical.JSONSchema = ""
+	canonical.Source = nil
+	canonical.Sort()
+
+	comment := func(c string) string {
+		if ignoreComments {
+			return ""
+		}
+		return strings.Join(strings.Fields(c), " ")
+	}
+	for _, def := range canonical.Definitions {
+		def.Comment = comment(def.Comment)
+		def.SourcePosition = nil
+		if ignoreComments {
+			def.Annotations = nil
+		}
+		for _, r := range def.Relations {
+			r.Comment = comment(r.Comment)
+			r.SourcePosition = nil
+			r.Index = 0
+			if ignoreComments {
+				r.Annotations = nil
+			}
+			sort.SliceStable(r.Types, func(i, j int) bool {
+				return relationTypeDSL(r.Types[i]) < relationTypeDSL(r.Types[j])
+			})
+		}
+		for _, p := range def.Permissions {
+			p.Comment = comment(p.Comment)
+			p.SourcePosition = nil
+			p.Index = 0
+			if ignoreComments {
+				p.Annotations = nil
+			}
+			canonicalUserSet(p.UserSet)
+			p.Expression = userSetExpression(p.UserSet)
+			sort.SliceStable(p.Arrows, func(i, j int) bool {
+				return p.Arrows[i].Via+"->"+p.Arrows[i].Target < p.Arrows[j].Via+"->"+p.Arrows[j].Target
+			})
+		}
+	}
+	for _, caveat := range canonical.Caveats {
+		caveat.Comment = comment(caveat.Comment)
+		caveat.ParameterOrder = nil
+		caveat.ExpressionAST = nil
+	}
+
+	data, err = json.Marshal(canonical)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalUserSet sorts the operands of unions and intersections, which don't change the
+// result, exclusions keep their order
+func canonicalUserSet(set *UserSet) {
+	if set == nil {
+		return
+	}
+	for _, child := range set.Children {
+		canonicalUserSet(child)
+	}
+	if set.Operation == "union" || set.Operation == "intersection" {
+		sort.SliceStable(set.Children, func(i, j int) bool {
+			return userSetExpression(set.Children[i]) < userSetExpression(set.Children[j])
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package spice2json
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Format writes the schema to w in one output format
+type Format func(schema *Schema, w io.Writer, opts Options) error
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]Format{
+		"json":             documentFormat("json"),
+		"yaml":             documentFormat("yaml"),
+		"toml":             documentFormat("toml"),
+		"ndjson":           writeNDJSON,
+		"dot":              bytesFormat(func(schema *Schema) ([]byte, error) { return writeDot(schema), nil }),
+		"mermaid":          bytesFormat(func(schema *Schema) ([]byte, error) { return writeMermaid(schema), nil }),
+		"plantuml":         bytesFormat(func(schema *Schema) ([]byte, error) { return writePlantUML(schema), nil }),
+		"csv":              bytesFormat(writeCSV),
+		"openapi-fragment": writeOpenAPIFragment,
+		"edges":            writeEdges,
+		"zed":              writeCanonicalDSL,
+		"sql":              bytesFormat(writeSQL),
+	}
+)
+
+func documentFormat(format string) Format {
+	return func(schema *Schema, w io.Writer, opts Options) error {
+		if opts.SnakeCaseKeys {
+			if format != "json" {
+				return fmt.Errorf("output format %q doesn't support snake_case keys, use json or ndjson", format)
+			}
+			return writeDocument(snakeCaseKeys(schema), w, format, opts.Indent)
+		}
+		return writeDocument(schema, w, format, opts.Indent)
+	}
+}
+
+// bytesFormat adapts a format rendering the whole output in memory
+func bytesFormat(render func(*Schema) ([]byte, error)) Format {
+	return func(schema *Schema, w io.Writer, opts Options) error {
+		data, err := render(schema)
+		if err != nil {
+			return fmt.Errorf("unable to serialize schema for export: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("unable to write schema for export: %w", err)
+		}
+		return nil
+	}
+}
+
+// RegisterFormat adds an output format for WriteAs, replacing the format with the same name
+func RegisterFormat(name string, format Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[name] = format
+}
+
+// Formats returns the names of the registered output formats, sorted
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteAs writes the schema to w in the registered format, json, ndjson, yaml, toml, dot,
+// mermaid, plantuml, csv, openapi-fragment, edges, zed, sql or one added with RegisterFormat
+func WriteAs(schema *Schema, format string, w io.Writer, opts Options) error {
+	formatsMu.RLock()
+	write, ok := formats[format]
+	formatsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	return write(schema, w, opts)
+}
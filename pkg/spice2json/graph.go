@@ -0,0 +1,119 @@
+package spice2json
+
+import "strings"
+
+// graphNode is a definition, relation or permission in the permission graph
+type graphNode struct {
+	ID         string
+	Label      string
+	Kind       string
+	Definition string
+}
+
+// graphEdge connects a relation to its subject type, or a permission to what it references
+type graphEdge struct {
+	From     string
+	To       string
+	Kind     string
+	Label    string
+	Wildcard bool
+	Caveat   string
+}
+
+// graph is the shared model behind the diagram output formats
+type graph struct {
+	Definitions []string
+	Nodes       []graphNode
+	Edges       []graphEdge
+}
+
+// qualifiedName returns namespace/name, names that already start with the namespace, like
+// relation types since output version 2, are returned as is
+func qualifiedName(name string, namespace string) string {
+	if namespace == "" || strings.HasPrefix(name, namespace+"/") {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+func memberID(definition string, member string) string {
+	return definition + ":" + member
+}
+
+// diagramID strips the namespace separator which mermaid and plantuml don't allow in class names
+func diagramID(name string) string {
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+func buildGraph(schema *Schema) *graph {
+	g := &graph{}
+	seen := map[graphEdge]bool{}
+	addEdge := func(e graphEdge) {
+		if !seen[e] {
+			seen[e] = true
+			g.Edges = append(g.Edges, e)
+		}
+	}
+
+	relations := map[string]*Relation{}
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, r := range def.Relations {
+			relations[memberID(defName, r.Name)] = r
+		}
+	}
+
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		g.Definitions = append(g.Definitions, defName)
+		g.Nodes = append(g.Nodes, graphNode{ID: defName, Label: defName, Kind: "definition", Definition: defName})
+
+		for _, r := range def.Relations {
+			id := memberID(defName, r.Name)
+			g.Nodes = append(g.Nodes, graphNode{ID: id, Label: r.Name, Kind: "relation", Definition: defName})
+			for _, t := range r.Types {
+				to := qualifiedName(t.Type, t.Namespace)
+				if t.Relation != "" && !t.Wildcard {
+					to = memberID(to, t.Relation)
+				}
+				addEdge(graphEdge{From: id, To: to, Kind: "subject", Wildcard: t.Wildcard, Caveat: t.Caveat})
+			}
+		}
+
+		for _, p := range def.Permissions {
+			id := memberID(defName, p.Name)
+			g.Nodes = append(g.Nodes, graphNode{ID: id, Label: p.Name, Kind: "permission", Definition: defName})
+			walkUserSet(p.UserSet, func(set *UserSet) {
+				if set.Relation == "" {
+					return
+				}
+				if set.Permission == "" {
+					addEdge(graphEdge{From: id, To: memberID(defName, set.Relation), Kind: "reference"})
+					return
+				}
+
+				tupleset, ok := relations[memberID(defName, set.Relation)]
+				if !ok {
+					return
+				}
+				for _, t := range tupleset.Types {
+					to := memberID(qualifiedName(t.Type, t.Namespace), set.Permission)
+					addEdge(graphEdge{From: id, To: to, Kind: "arrow", Label: set.Relation})
+				}
+			})
+		}
+	}
+
+	return g
+}
+
+// walkUserSet calls fn for the user set and each of its descendants
+func walkUserSet(set *UserSet, fn func(*UserSet)) {
+	if set == nil {
+		return
+	}
+	fn(set)
+	for _, child := range set.Children {
+		walkUserSet(child, fn)
+	}
+}
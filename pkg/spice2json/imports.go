@@ -0,0 +1,117 @@
+package spice2json
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/schemadsl/lexer"
+)
+
+// schemaImport is an import "path" statement, from start to end in the source
+type schemaImport struct {
+	Path  string
+	Start int
+	End   int
+}
+
+// schemaImports scans the schema source for top level import statements
+func schemaImports(source string) []schemaImport {
+	lex := lexer.NewPeekableLexer(lexer.Lex(input.Source("schema"), source))
+	defer lex.Close()
+
+	var imports []schemaImport
+	depth := 0
+	start := -1
+	for {
+		token := lex.NextToken()
+		switch token.Kind {
+		case lexer.TokenTypeEOF, lexer.TokenTypeError:
+			return imports
+		case lexer.TokenTypeWhitespace, lexer.TokenTypeSinglelineComment, lexer.TokenTypeMultilineComment:
+			continue
+		case lexer.TokenTypeLeftBrace:
+			depth++
+		case lexer.TokenTypeRightBrace:
+			depth--
+		case lexer.TokenTypeIdentifier:
+			if depth == 0 && token.Value == "import" {
+				start = int(token.Position)
+				continue
+			}
+		case lexer.TokenTypeString:
+			if start >= 0 {
+				imports = append(imports, schemaImport{
+					Path:  strings.Trim(token.Value, `"'`),
+					Start: start,
+					End:   int(token.Position) + len(token.Value),
+				})
+			}
+		}
+		start = -1
+	}
+}
+
+// StripImports replaces the import statements with spaces, which this SpiceDB version can't
+// compile, keeping the line breaks so positions in compiler errors still match the file
+func StripImports(source string) string {
+	imports := schemaImports(source)
+	if len(imports) == 0 {
+		return source
+	}
+	blanked := []byte(source)
+	for _, imp := range imports {
+		for i := imp.Start; i < imp.End; i++ {
+			if blanked[i] != '\n' {
+				blanked[i] = ' '
+			}
+		}
+	}
+	return string(blanked)
+}
+
+// ResolveImports reads the root schema file and follows its import "path" statements, relative
+// to the directory of the importing file. The files are returned imported files first and each
+// only once, their import statements have to be removed with StripImports before compiling.
+// Circular imports are an error.
+func ResolveImports(root string, read func(path string) (string, error)) ([]SourceFile, error) {
+	var files []SourceFile
+	done := map[string]bool{}
+	var stack []string
+
+	var load func(path string) error
+	load = func(path string) error {
+		path = filepath.Clean(path)
+		for i, p := range stack {
+			if p == path {
+				return fmt.Errorf("circular import %s", strings.Join(append(stack[i:], path), " -> "))
+			}
+		}
+		if done[path] {
+			return nil
+		}
+
+		source, err := read(path)
+		if err != nil {
+			return err
+		}
+		stack = append(stack, path)
+		imports := schemaImports(source)
+		for _, imp := range imports {
+			if err := load(filepath.Join(filepath.Dir(path), imp.Path)); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+
+		done[path] = true
+		files = append(files, SourceFile{Name: path, Source: source})
+		return nil
+	}
+
+	if err := load(root); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
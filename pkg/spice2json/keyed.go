@@ -0,0 +1,46 @@
+package spice2json
+
+import (
+	"fmt"
+	"io"
+)
+
+// KeyedSchema is the Schema with definitions keyed by namespace/name and caveats keyed by name.
+// The declaration order is lost, json, yaml and toml write the keys sorted.
+type KeyedSchema struct {
+	JSONSchema  string                 `json:"$schema,omitempty" yaml:"$schema,omitempty" toml:"$schema,omitempty"`
+	Version     string                 `json:"version" yaml:"version" toml:"version"`
+	Definitions map[string]*Definition `json:"definitions" yaml:"definitions" toml:"definitions"`
+	Caveats     map[string]*Caveat     `json:"caveats,omitempty" yaml:"caveats,omitempty" toml:"caveats,omitempty"`
+	Source      any                    `json:"source,omitempty" yaml:"source,omitempty" toml:"source,omitempty"`
+}
+
+// Keyed returns the schema with definitions and caveats keyed by name
+func (s *Schema) Keyed() *KeyedSchema {
+	keyed := &KeyedSchema{
+		JSONSchema:  s.JSONSchema,
+		Version:     s.Version,
+		Definitions: map[string]*Definition{},
+		Source:      s.Source,
+	}
+	for _, def := range s.Definitions {
+		keyed.Definitions[qualifiedName(def.Name, def.Namespace)] = def
+	}
+	if len(s.Caveats) > 0 {
+		keyed.Caveats = map[string]*Caveat{}
+		for _, caveat := range s.Caveats {
+			keyed.Caveats[caveat.Name] = caveat
+		}
+	}
+	return keyed
+}
+
+// WriteKeyedSchemaIndentTo writes the keyed schema as json, yaml or toml, json is indented like
+// WriteSchemaIndentTo
+func WriteKeyedSchemaIndentTo(schema *KeyedSchema, w io.Writer, format string, indent string) error {
+	switch format {
+	case "json", "yaml", "toml":
+		return writeDocument(schema, w, format, indent)
+	}
+	return fmt.Errorf("output format %q doesn't support definitions keyed by name, use json, yaml or toml", format)
+}
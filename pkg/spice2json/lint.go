@@ -0,0 +1,329 @@
+package spice2json
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Warning is a lint finding, located at definition#member
+type Warning struct {
+	Check    string `json:"check"`
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s (%s)", w.Location, w.Message, w.Check)
+}
+
+// DefaultNamingPattern is the snake_case pattern the naming check expects by default
+var DefaultNamingPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// LintOptions selects the lint checks and configures them, the zero value runs all checks
+// with the default naming patterns
+type LintOptions struct {
+	// Checks are the names of the checks to run, all checks when empty
+	Checks []string
+
+	// MemberNames is the pattern relation and permission names have to match
+	MemberNames *regexp.Regexp
+
+	// DefinitionNames is the pattern definition names without namespace have to match
+	DefinitionNames *regexp.Regexp
+}
+
+type lintCheck struct {
+	Name  string
+	Check func(*Schema, LintOptions) []Warning
+}
+
+// lintChecks are the checks run by Lint in order, named like the check of their warnings
+var lintChecks = []lintCheck{
+	{"unused", func(schema *Schema, _ LintOptions) []Warning { return lintUnused(schema) }},
+	{"cycles", func(schema *Schema, _ LintOptions) []Warning { return lintCycles(schema) }},
+	// unknown relation kinds are reported while mapping with Options.SkipUnknown
+	{"unknown", func(*Schema, LintOptions) []Warning { return nil }},
+	{"naming", lintNaming},
+	{"dangling", func(schema *Schema, _ LintOptions) []Warning { return lintDangling(schema) }},
+}
+
+// LintChecks returns the names of all lint checks
+func LintChecks() []string {
+	names := make([]string, len(lintChecks))
+	for i, check := range lintChecks {
+		names[i] = check.Name
+	}
+	return names
+}
+
+// Lint checks the schema for common mistakes
+func Lint(schema *Schema) []Warning {
+	warnings, _ := LintWith(schema, LintOptions{})
+	return warnings
+}
+
+// LintWith is Lint with the checks selected and configured by opts, an unknown check name
+// is an error
+func LintWith(schema *Schema, opts LintOptions) ([]Warning, error) {
+	names := LintChecks()
+	for _, name := range opts.Checks {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("unknown lint check %q, use %s", name, strings.Join(names, ", "))
+		}
+	}
+	if len(opts.Checks) == 0 {
+		opts.Checks = names
+	}
+	if opts.MemberNames == nil {
+		opts.MemberNames = DefaultNamingPattern
+	}
+	if opts.DefinitionNames == nil {
+		opts.DefinitionNames = DefaultNamingPattern
+	}
+
+	var warnings []Warning
+	for _, check := range lintChecks {
+		if slices.Contains(opts.Checks, check.Name) {
+			warnings = append(warnings, check.Check(schema, opts)...)
+		}
+	}
+	return warnings, nil
+}
+
+// lintNaming reports definitions, relations and permissions not matching the naming patterns
+func lintNaming(schema *Schema, opts LintOptions) []Warning {
+	var warnings []Warning
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		if !opts.DefinitionNames.MatchString(def.Name) {
+			warnings = append(warnings, Warning{
+				Check:    "naming",
+				Location: defName,
+				Message:  "definition name doesn't match " + opts.DefinitionNames.String(),
+			})
+		}
+		for _, r := range def.Relations {
+			if !opts.MemberNames.MatchString(r.Name) {
+				warnings = append(warnings, Warning{
+					Check:    "naming",
+					Location: defName + "#" + r.Name,
+					Message:  "relation name doesn't match " + opts.MemberNames.String(),
+				})
+			}
+		}
+		for _, p := range def.Permissions {
+			if !opts.MemberNames.MatchString(p.Name) {
+				warnings = append(warnings, Warning{
+					Check:    "naming",
+					Location: defName + "#" + p.Name,
+					Message:  "permission name doesn't match " + opts.MemberNames.String(),
+				})
+			}
+		}
+	}
+	return warnings
+}
+
+// lintUnused reports relations that no permission and no subject relation refers to
+func lintUnused(schema *Schema) []Warning {
+	relations := map[string]*Relation{}
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, r := range def.Relations {
+			relations[memberID(defName, r.Name)] = r
+		}
+	}
+
+	used := map[string]bool{}
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, r := range def.Relations {
+			for _, t := range r.Types {
+				if t.Relation != "" && !t.Wildcard {
+					used[memberID(qualifiedName(t.Type, t.Namespace), t.Relation)] = true
+				}
+			}
+		}
+
+		for _, p := range def.Permissions {
+			walkUserSet(p.UserSet, func(set *UserSet) {
+				if set.Relation == "" {
+					return
+				}
+				used[memberID(defName, set.Relation)] = true
+				if set.Permission == "" {
+					return
+				}
+
+				// the arrow's right hand side lives on the subject types of the tupleset relation
+				if tupleset, ok := relations[memberID(defName, set.Relation)]; ok {
+					for _, t := range tupleset.Types {
+						used[memberID(qualifiedName(t.Type, t.Namespace), set.Permission)] = true
+					}
+				}
+			})
+		}
+	}
+
+	var warnings []Warning
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, r := range def.Relations {
+			if !used[memberID(defName, r.Name)] {
+				warnings = append(warnings, Warning{
+					Check:    "unused",
+					Location: defName + "#" + r.Name,
+					Message:  "relation is not used by any permission",
+				})
+			}
+		}
+	}
+	return warnings
+}
+
+// lintDangling reports relations and permissions referenced by permissions that don't exist,
+// and arrows whose relation is missing or whose permission exists on none of the subject types
+// of the relation. Like SpiceDB, an arrow only has to resolve on some of the subject types.
+// Subject types without a definition in the schema, e.g. filtered out, are skipped.
+func lintDangling(schema *Schema) []Warning {
+	definitions := definitionsByName(schema)
+	var warnings []Warning
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, p := range def.Permissions {
+			location := defName + "#" + p.Name
+			walkUserSet(p.UserSet, func(set *UserSet) {
+				if set.Relation == "" {
+					return
+				}
+				if set.Permission == "" {
+					if !hasMember(def, set.Relation) {
+						warnings = append(warnings, Warning{
+							Check:    "dangling",
+							Location: location,
+							Message:  fmt.Sprintf("references %s#%s which doesn't exist", defName, set.Relation),
+						})
+					}
+					return
+				}
+
+				tupleset := findRelation(def, set.Relation)
+				if tupleset == nil {
+					warnings = append(warnings, Warning{
+						Check:    "dangling",
+						Location: location,
+						Message:  fmt.Sprintf("arrow %s->%s uses relation %s#%s which doesn't exist", set.Relation, set.Permission, defName, set.Relation),
+					})
+					return
+				}
+				var missing []string
+				found := false
+				for _, t := range tupleset.Types {
+					subject := qualifiedName(t.Type, t.Namespace)
+					subjectDef, ok := definitions[subject]
+					if !ok || t.Wildcard {
+						continue
+					}
+					if hasMember(subjectDef, set.Permission) {
+						found = true
+					} else if !slices.Contains(missing, subject) {
+						missing = append(missing, subject)
+					}
+				}
+				if !found && len(missing) > 0 {
+					warnings = append(warnings, Warning{
+						Check:    "dangling",
+						Location: location,
+						Message:  fmt.Sprintf("arrow %s->%s references %s on %s which doesn't exist", set.Relation, set.Permission, set.Permission, strings.Join(missing, ", ")),
+					})
+				}
+			})
+		}
+	}
+	return warnings
+}
+
+func findRelation(def *Definition, name string) *Relation {
+	for _, r := range def.Relations {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// hasMember reports whether the definition has a relation or permission with the name
+func hasMember(def *Definition, name string) bool {
+	if findRelation(def, name) != nil {
+		return true
+	}
+	for _, p := range def.Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// lintCycles reports permissions that depend on themselves through computed user sets referencing
+// other permissions of the same definition. Arrows are not followed, recursion through a relation
+// such as parent->view is resolved over relationships and is fine.
+func lintCycles(schema *Schema) []Warning {
+	var warnings []Warning
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+
+		dependencies := map[string][]string{}
+		for _, p := range def.Permissions {
+			dependencies[p.Name] = nil
+		}
+		for _, p := range def.Permissions {
+			walkUserSet(p.UserSet, func(set *UserSet) {
+				if _, ok := dependencies[set.Relation]; ok && set.Permission == "" {
+					dependencies[p.Name] = append(dependencies[p.Name], set.Relation)
+				}
+			})
+		}
+
+		visited := map[string]bool{}
+		onStack := map[string]bool{}
+		var stack []string
+		var visit func(name string)
+		visit = func(name string) {
+			visited[name] = true
+			onStack[name] = true
+			stack = append(stack, name)
+			for _, dep := range dependencies[name] {
+				if onStack[dep] {
+					var path []string
+					for i := len(stack) - 1; i >= 0; i-- {
+						if stack[i] == dep {
+							for _, member := range append(stack[i:], dep) {
+								path = append(path, defName+"#"+member)
+							}
+							break
+						}
+					}
+					warnings = append(warnings, Warning{
+						Check:    "cycles",
+						Location: defName + "#" + dep,
+						Message:  "permission cycle " + strings.Join(path, " -> "),
+					})
+				} else if !visited[dep] {
+					visit(dep)
+				}
+			}
+			stack = stack[:len(stack)-1]
+			onStack[name] = false
+		}
+
+		for _, p := range def.Permissions {
+			if !visited[p.Name] {
+				visit(p.Name)
+			}
+		}
+	}
+	return warnings
+}
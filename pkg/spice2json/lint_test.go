@@ -0,0 +1,42 @@
+package spice2json
+
+import (
+	"os"
+	"testing"
+)
+
+// lintSource converts the schema and runs the lint check on it
+func lintSource(t *testing.T, source string, check string) []Warning {
+	t.Helper()
+	schema, err := Convert(source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings, err := LintWith(schema, LintOptions{Checks: []string{check}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return warnings
+}
+
+func TestLintCycles(t *testing.T) {
+	source, err := os.ReadFile("../../example/cycle.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := lintSource(t, string(source), "cycles")
+	if len(warnings) != 1 {
+		t.Fatalf("got %v, want one cycle", warnings)
+	}
+	if want := "permission cycle document#view -> document#edit -> document#view"; warnings[0].Message != want {
+		t.Errorf("got %q, want %q", warnings[0].Message, want)
+	}
+}
+
+func TestLintCyclesThroughArrowsAreFine(t *testing.T) {
+	// recursion through a relation ends at the data, it isn't a cycle
+	source := "definition user {}\ndefinition folder {\n\trelation parent: folder\n\trelation viewer: user\n\tpermission view = viewer + parent->view\n}\n"
+	if warnings := lintSource(t, source, "cycles"); len(warnings) != 0 {
+		t.Errorf("got %v, want no cycles", warnings)
+	}
+}
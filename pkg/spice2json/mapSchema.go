@@ -0,0 +1,517 @@
+package spice2json
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/authzed/spicedb/pkg/caveats"
+	caveattypes "github.com/authzed/spicedb/pkg/caveats/types"
+	"github.com/authzed/spicedb/pkg/namespace"
+	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+	implv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// splitNamespace splits on the last /, so in org/team/document the namespace is org/team
+// and the name is document
+func splitNamespace(fullname string) (string, string) {
+	i := strings.LastIndex(fullname, "/")
+	if i < 0 {
+		return fullname, ""
+	}
+	return fullname[i+1:], fullname[:i]
+}
+
+func mapDefinition(def *corev1.NamespaceDefinition, opts Options) (*Definition, error) {
+	var relations []*Relation
+	var permissions []*Permission
+	caveats := map[string]relationCaveat{}
+	if !opts.NoCaveats {
+		caveats = relationCaveats(def)
+	}
+	for i, r := range def.Relation {
+		kind := namespace.GetRelationKind(r)
+		if kind == implv1.RelationMetadata_PERMISSION {
+			p := mapPermission(def.Name, r, caveats, opts)
+			p.Index = i
+			permissions = append(permissions, p)
+		} else if kind == implv1.RelationMetadata_RELATION {
+			relation := mapRelation(r, opts)
+			relation.Index = i
+			relations = append(relations, relation)
+		} else if opts.SkipUnknown {
+			if opts.OnWarning != nil {
+				opts.OnWarning(Warning{
+					Check:    "unknown",
+					Location: def.Name + "#" + r.Name,
+					Message:  "skipped, neither permission nor relation",
+				})
+			}
+		} else {
+			return nil, fmt.Errorf("unexpected relation %q, neither permission nor relation", r.Name)
+		}
+	}
+
+	name, ns := splitNamespace(def.Name)
+	comment, annotations := mapComment(def.GetMetadata(), opts)
+
+	return &Definition{
+		Name:           name,
+		Namespace:      ns,
+		Relations:      relations,
+		Permissions:    permissions,
+		Comment:        comment,
+		Annotations:    annotations,
+		Metadata:       mapMetadata(def.GetMetadata(), opts),
+		SourcePosition: mapSourcePosition(def.GetSourcePosition(), opts),
+	}, nil
+}
+
+func mapRelation(relation *corev1.Relation, opts Options) *Relation {
+	// relations without type information, e.g. synthetic ones, get an empty list of types
+	types := []*RelationType{}
+	seen := map[string]bool{}
+	for _, t := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		relationType := mapRelationType(t, opts)
+		// without caveats user with cav | user are the same type
+		if opts.NoCaveats && seen[relationTypeDSL(relationType)] {
+			continue
+		}
+		seen[relationTypeDSL(relationType)] = true
+		types = append(types, relationType)
+	}
+
+	comment, annotations := mapComment(relation.GetMetadata(), opts)
+	return &Relation{
+		Name:           relation.Name,
+		Comment:        comment,
+		Annotations:    annotations,
+		Metadata:       mapMetadata(relation.GetMetadata(), opts),
+		Types:          types,
+		SourcePosition: mapSourcePosition(relation.GetSourcePosition(), opts),
+	}
+}
+
+// relationCaveat holds the caveats required by the allowed types of a relation. Optional is
+// set when the relation also allows a type without caveat, so a subject may not need one.
+type relationCaveat struct {
+	Names    []string
+	Optional bool
+}
+
+// relationCaveats collects the caveats of each relation in the definition with at least one
+// caveated allowed type, keyed by relation name
+func relationCaveats(def *corev1.NamespaceDefinition) map[string]relationCaveat {
+	caveats := map[string]relationCaveat{}
+	for _, r := range def.Relation {
+		var c relationCaveat
+		for _, t := range r.GetTypeInformation().GetAllowedDirectRelations() {
+			name := t.GetRequiredCaveat().GetCaveatName()
+			if name == "" {
+				c.Optional = true
+			} else if !slices.Contains(c.Names, name) {
+				c.Names = append(c.Names, name)
+			}
+		}
+		if len(c.Names) > 0 {
+			caveats[r.Name] = c
+		}
+	}
+	return caveats
+}
+
+func mapPermission(defName string, relation *corev1.Relation, caveats map[string]relationCaveat, opts Options) *Permission {
+	var arrows []*Arrow
+	userSet := mapUserSet(relation.GetUsersetRewrite(), caveats, &arrows, opts)
+	// a missing or empty rewrite, e.g. from a partial compilation, is marked as a nil leaf
+	// rather than a null user set
+	if userSet == nil {
+		userSet = &UserSet{Operation: "nil"}
+		if opts.OnWarning != nil {
+			opts.OnWarning(Warning{
+				Check:    "empty-rewrite",
+				Location: defName + "#" + relation.Name,
+				Message:  "permission has no user set rewrite",
+			})
+		}
+	}
+	comment, annotations := mapComment(relation.GetMetadata(), opts)
+	return &Permission{
+		Name:           relation.Name,
+		UserSet:        userSet,
+		Expression:     userSetExpression(userSet),
+		IsAlias:        isAlias(userSet),
+		Arrows:         arrows,
+		Comment:        comment,
+		Annotations:    annotations,
+		Metadata:       mapMetadata(relation.GetMetadata(), opts),
+		SourcePosition: mapSourcePosition(relation.GetSourcePosition(), opts),
+	}
+}
+
+// isAlias reports whether the user set is a single relation or permission of the same
+// definition, the compiler wraps it in a union with one child
+func isAlias(set *UserSet) bool {
+	if set != nil && set.Operation == "union" && len(set.Children) == 1 {
+		set = set.Children[0]
+	}
+	return set != nil && set.Operation == "" && set.Relation != "" && set.Permission == ""
+}
+
+// mapSourcePosition converts the zero indexed proto position into the one indexed line and
+// column also used in compiler errors
+func mapSourcePosition(position *corev1.SourcePosition, opts Options) *SourcePosition {
+	if !opts.Positions || position == nil {
+		return nil
+	}
+	return &SourcePosition{
+		Line:   int(position.ZeroIndexedLineNumber) + 1,
+		Column: int(position.ZeroIndexedColumnPosition) + 1,
+	}
+}
+
+// mapUserSet maps the rewrite tree, adding each arrow it contains to arrows
+func mapUserSet(userset *corev1.UsersetRewrite, caveats map[string]relationCaveat, arrows *[]*Arrow, opts Options) *UserSet {
+	union := userset.GetUnion()
+	if union != nil {
+		return &UserSet{
+			Operation: "union",
+			Children:  mapUserSetChild(union.GetChild(), caveats, arrows, opts),
+		}
+	}
+
+	intersection := userset.GetIntersection()
+	if intersection != nil {
+		return &UserSet{
+			Operation: "intersection",
+			Children:  mapUserSetChild(intersection.GetChild(), caveats, arrows, opts),
+		}
+	}
+
+	exclusion := userset.GetExclusion()
+	if exclusion != nil {
+		return &UserSet{
+			Operation: "exclusion",
+			Children:  mapUserSetChild(exclusion.GetChild(), caveats, arrows, opts),
+		}
+	}
+
+	return nil
+}
+
+func mapUserSetChild(children []*corev1.SetOperation_Child, caveats map[string]relationCaveat, arrows *[]*Arrow, opts Options) []*UserSet {
+	var sets []*UserSet
+	for _, child := range children {
+		computed := child.GetComputedUserset()
+		if computed != nil {
+			sets = append(sets, &UserSet{
+				Relation:       computed.Relation,
+				Caveats:        caveats[computed.Relation].Names,
+				CaveatOptional: caveats[computed.Relation].Optional,
+			})
+		}
+
+		tuple := child.GetTupleToUserset()
+		if tuple != nil {
+			sets = append(sets, &UserSet{
+				Relation:       tuple.Tupleset.Relation,
+				Permission:     tuple.ComputedUserset.Relation,
+				Caveats:        caveats[tuple.Tupleset.Relation].Names,
+				CaveatOptional: caveats[tuple.Tupleset.Relation].Optional,
+			})
+			*arrows = append(*arrows, &Arrow{Via: tuple.Tupleset.Relation, Target: tuple.ComputedUserset.Relation})
+		}
+
+		set := child.GetUsersetRewrite()
+		if set != nil {
+			sets = append(sets, mapUserSet(set, caveats, arrows, opts))
+		}
+
+		// nil children don't change the result, they are only kept to reproduce the expression.
+		// The compiler sets the oneof without a message, so GetXNil is always nil.
+		if _, isNil := child.ChildType.(*corev1.SetOperation_Child_XNil); opts.FaithfulTree && isNil {
+			sets = append(sets, &UserSet{Operation: "nil"})
+		}
+	}
+	return sets
+}
+
+func mapRelationType(relationType *corev1.AllowedRelation, opts Options) *RelationType {
+	_, ns := splitNamespace(relationType.Namespace)
+
+	var relationName string
+	wildcard := false
+	switch v := relationType.RelationOrWildcard.(type) {
+	case *corev1.AllowedRelation_Relation:
+		relationName = v.Relation
+
+		if relationName == "..." {
+			relationName = ""
+		}
+
+	case *corev1.AllowedRelation_PublicWildcard_:
+		// relation stays "*" for existing consumers, wildcard distinguishes it from a subject relation
+		if !opts.ExpandWildcards {
+			relationName = "*"
+		}
+		wildcard = true
+	}
+
+	caveat := relationType.RequiredCaveat
+	var caveatName string
+	if caveat != nil && !opts.NoCaveats {
+		caveatName = caveat.CaveatName
+	} else {
+		caveatName = ""
+	}
+	var subjectType string
+	if opts.QualifiedSubjects {
+		subjectType = relationType.Namespace
+		switch {
+		case wildcard:
+			subjectType += ":*"
+		case relationName != "":
+			subjectType += "#" + relationName
+		}
+	}
+
+	return &RelationType{
+		Type:        relationType.Namespace,
+		Namespace:   ns,
+		Relation:    relationName,
+		Wildcard:    wildcard,
+		Caveat:      caveatName,
+		SubjectType: subjectType,
+	}
+}
+
+var commentRegex = regexp.MustCompile("(/[*]{1,2} ?|// ?| ?[*] | ?[*]?/)")
+
+// rawCommentRegex only matches the comment markers and the leading * of block comment lines,
+// the compiler already trims the whitespace around each line
+var rawCommentRegex = regexp.MustCompile("(?m)[ \t]*[*]/\\z|^(/[*]{1,2} ?|// ?|[*] ?)")
+
+func getMetadataComments(metaData *corev1.Metadata, opts Options) string {
+	if opts.NoComments {
+		return ""
+	}
+
+	comment := ""
+	for _, message := range decodeMetadata(metaData) {
+		doc, ok := message.(*implv1.DocComment)
+		if !ok {
+			continue
+		}
+		if opts.RawComments {
+			comment += rawCommentRegex.ReplaceAllString(doc.GetComment(), "") + "\n"
+		} else {
+			comment += commentRegex.ReplaceAllString(doc.GetComment(), "") + "\n"
+		}
+	}
+	if opts.RawComments {
+		return strings.TrimRight(strings.TrimLeft(comment, "\n"), " \t\n")
+	}
+	return strings.TrimSpace(comment)
+}
+
+func mapCaveat(caveat *corev1.CaveatDefinition, opts Options) (*Caveat, error) {
+	parameters := map[string]string{}
+	parameterTypes := map[string]string{}
+	for key, value := range caveat.ParameterTypes {
+		parameters[key] = value.TypeName
+		normalized, err := normalizeCaveatType(value)
+		if err != nil {
+			if opts.OnWarning != nil {
+				opts.OnWarning(Warning{Check: "caveat-type", Location: caveat.Name + "(" + key + ")", Message: err.Error()})
+			}
+			normalized = value.TypeName
+		}
+		parameterTypes[key] = normalized
+	}
+
+	expression, err := caveatExpression(caveat)
+	if err != nil {
+		return nil, err
+	}
+
+	var ast any
+	if opts.CaveatAST {
+		if ast, err = caveatExpressionAST(caveat); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Caveat{
+		Name:           caveat.Name,
+		Parameters:     parameters,
+		ParameterTypes: parameterTypes,
+		ParameterOrder: sortedParameterNames(parameters),
+		Expression:     expression,
+		ExpressionAST:  ast,
+		Comment:        getMetadataComments(caveat.Metadata, opts),
+		Metadata:       mapMetadata(caveat.Metadata, opts),
+	}, nil
+}
+
+// caveatExpression decodes the serialized CEL expression of the caveat back into source form
+func caveatExpression(caveat *corev1.CaveatDefinition) (string, error) {
+	if len(caveat.SerializedExpression) == 0 {
+		return "", nil
+	}
+	parameterTypes, err := caveattypes.DecodeParameterTypes(caveat.ParameterTypes)
+	if err != nil {
+		return "", err
+	}
+	compiled, err := caveats.DeserializeCaveat(caveat.SerializedExpression, parameterTypes)
+	if err != nil {
+		return "", err
+	}
+	return compiled.ExprString()
+}
+
+// caveatExpressionAST decodes the serialized caveat into its checked CEL expression, as the
+// protojson object so it can be written in every output format
+func caveatExpressionAST(caveat *corev1.CaveatDefinition) (any, error) {
+	if len(caveat.SerializedExpression) == 0 {
+		return nil, nil
+	}
+	decoded := &implv1.DecodedCaveat{}
+	if err := decoded.UnmarshalVT(caveat.SerializedExpression); err != nil {
+		return nil, fmt.Errorf("unable to decode caveat %s: %w", caveat.Name, err)
+	}
+	data, err := protojson.Marshal(decoded.GetCel())
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the expression of caveat %s: %w", caveat.Name, err)
+	}
+	var ast any
+	if err := json.Unmarshal(data, &ast); err != nil {
+		return nil, err
+	}
+	return ast, nil
+}
+
+type Definition struct {
+	Name        string        `json:"name" yaml:"name" toml:"name"`
+	Namespace   string        `json:"namespace,omitempty" yaml:"namespace,omitempty" toml:"namespace,omitempty"`
+	Relations   []*Relation   `json:"relations,omitempty" yaml:"relations,omitempty" toml:"relations,omitempty"`
+	Permissions []*Permission `json:"permissions,omitempty" yaml:"permissions,omitempty" toml:"permissions,omitempty"`
+	Comment     string        `json:"comment,omitempty" yaml:"comment,omitempty" toml:"comment,omitempty"`
+	// SourcePosition is only set with Options.Positions
+	SourcePosition *SourcePosition `json:"sourcePosition,omitempty" yaml:"sourcePosition,omitempty" toml:"sourcePosition,omitempty"`
+	// Metadata holds the entries of Options.MetadataExtractors
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty" toml:"metadata,omitempty"`
+	// Annotations are the @key: value and @flag comment lines, only set with Options.ParseAnnotations
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty" toml:"annotations,omitempty"`
+	// Orphan is only set by Schema.MarkOrphans
+	Orphan bool `json:"orphan,omitempty" yaml:"orphan,omitempty" toml:"orphan,omitempty"`
+}
+
+type SourcePosition struct {
+	Line   int `json:"line" yaml:"line" toml:"line"`
+	Column int `json:"column" yaml:"column" toml:"column"`
+}
+
+type Relation struct {
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// Index is the position among the relations and permissions of the definition in the source
+	Index   int             `json:"index" yaml:"index" toml:"index"`
+	Types   []*RelationType `json:"types" yaml:"types" toml:"types"`
+	Comment string          `json:"comment,omitempty" yaml:"comment,omitempty" toml:"comment,omitempty"`
+	// SourcePosition is only set with Options.Positions
+	SourcePosition *SourcePosition `json:"sourcePosition,omitempty" yaml:"sourcePosition,omitempty" toml:"sourcePosition,omitempty"`
+	// Metadata holds the entries of Options.MetadataExtractors
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty" toml:"metadata,omitempty"`
+	// Annotations are the @key: value and @flag comment lines, only set with Options.ParseAnnotations
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty" toml:"annotations,omitempty"`
+}
+
+type RelationType struct {
+	// Type is the full subject definition name, e.g. billing/account, Namespace is its
+	// namespace on its own
+	Type      string `json:"type" yaml:"type" toml:"type"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty" toml:"namespace,omitempty"`
+	Relation  string `json:"relation,omitempty" yaml:"relation,omitempty" toml:"relation,omitempty"`
+	Wildcard  bool   `json:"wildcard,omitempty" yaml:"wildcard,omitempty" toml:"wildcard,omitempty"`
+	// Caveat is the caveat required by this allowed type only, e.g. on_weekdays for
+	// user with on_weekdays. SpiceDB only stores the caveat name on the relation type, the
+	// context it expects are the parameters of the caveat, see Options.InlineCaveats.
+	Caveat string `json:"caveat,omitempty" yaml:"caveat,omitempty" toml:"caveat,omitempty"`
+	// SubjectType is the namespace/type#relation subject as a single string, only set with
+	// Options.QualifiedSubjects
+	SubjectType string `json:"subjectType,omitempty" yaml:"subjectType,omitempty" toml:"subjectType,omitempty"`
+	// CaveatDefinition is the full caveat, only set with Options.InlineCaveats
+	CaveatDefinition *Caveat `json:"caveatDefinition,omitempty" yaml:"caveatDefinition,omitempty" toml:"caveatDefinition,omitempty"`
+}
+
+type Permission struct {
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// Index is the position among the relations and permissions of the definition in the source
+	Index   int      `json:"index" yaml:"index" toml:"index"`
+	UserSet *UserSet `json:"userSet" yaml:"userSet" toml:"userSet"`
+	// Expression is the user set written as a schema expression, e.g. "viewer + parent->view"
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty" toml:"expression,omitempty"`
+	// IsAlias is set for permissions that are just another relation or permission, e.g. "view = viewer"
+	IsAlias bool `json:"isAlias,omitempty" yaml:"isAlias,omitempty" toml:"isAlias,omitempty"`
+	// Arrows are all relation->permission arrows of the user set, in expression order
+	Arrows  []*Arrow `json:"arrows,omitempty" yaml:"arrows,omitempty" toml:"arrows,omitempty"`
+	Comment string   `json:"comment,omitempty" yaml:"comment,omitempty" toml:"comment,omitempty"`
+	// SourcePosition is only set with Options.Positions
+	SourcePosition *SourcePosition `json:"sourcePosition,omitempty" yaml:"sourcePosition,omitempty" toml:"sourcePosition,omitempty"`
+	// ResolvedSubjects is only set by Schema.ResolveSubjects
+	ResolvedSubjects []string `json:"resolvedSubjects,omitempty" yaml:"resolvedSubjects,omitempty" toml:"resolvedSubjects,omitempty"`
+	// Metadata holds the entries of Options.MetadataExtractors
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty" toml:"metadata,omitempty"`
+	// Annotations are the @key: value and @flag comment lines, only set with Options.ParseAnnotations
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty" toml:"annotations,omitempty"`
+}
+
+// Arrow is a via->target arrow, following the via relation to the target permission or relation
+// of its subjects
+type Arrow struct {
+	Via    string `json:"via" yaml:"via" toml:"via"`
+	Target string `json:"target" yaml:"target" toml:"target"`
+}
+
+type UserSet struct {
+	Operation  string `json:"operation,omitempty" yaml:"operation,omitempty" toml:"operation,omitempty"`
+	Relation   string `json:"relation,omitempty" yaml:"relation,omitempty" toml:"relation,omitempty"`
+	Permission string `json:"permission,omitempty" yaml:"permission,omitempty" toml:"permission,omitempty"`
+	// Caveats are the caveats required by the allowed types of the referenced relation
+	Caveats []string `json:"caveats,omitempty" yaml:"caveats,omitempty" toml:"caveats,omitempty"`
+	// CaveatOptional is set with Caveats when the relation also allows a type without caveat
+	CaveatOptional bool       `json:"caveatOptional,omitempty" yaml:"caveatOptional,omitempty" toml:"caveatOptional,omitempty"`
+	Children       []*UserSet `json:"children,omitempty" yaml:"children,omitempty" toml:"children,omitempty"`
+	// Kind tells whether the permission of an arrow is a permission or a relation on the subject
+	// types of the relation, mixed when it differs between them
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty" toml:"kind,omitempty"`
+}
+
+type Caveat struct {
+	Name       string            `json:"name" yaml:"name" toml:"name"`
+	Parameters map[string]string `json:"parameters" yaml:"parameters" toml:"parameters"`
+	// ParameterTypes holds the normalized type of each parameter, with the type arguments of
+	// list and map, e.g. list<string>. Parameters keeps the type names as SpiceDB reports them.
+	ParameterTypes map[string]string `json:"parameterTypes,omitempty" yaml:"parameterTypes,omitempty" toml:"parameterTypes,omitempty"`
+	// ParameterOrder lists the parameter names in declaration order when converted from
+	// source, otherwise sorted by name
+	ParameterOrder []string `json:"parameterOrder,omitempty" yaml:"parameterOrder,omitempty" toml:"parameterOrder,omitempty"`
+	// Expression is the CEL expression of the caveat, as formatted by the CEL library
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty" toml:"expression,omitempty"`
+	// ExpressionAST is the checked CEL expression as protojson, only set with Options.CaveatAST
+	ExpressionAST any    `json:"expressionAst,omitempty" yaml:"expressionAst,omitempty" toml:"expressionAst,omitempty"`
+	Comment       string `json:"comment,omitempty" yaml:"comment,omitempty" toml:"comment,omitempty"`
+	// Metadata holds the entries of Options.MetadataExtractors
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty" toml:"metadata,omitempty"`
+}
+
+type Schema struct {
+	JSONSchema  string        `json:"$schema,omitempty" yaml:"$schema,omitempty" toml:"$schema,omitempty"`
+	Version     string        `json:"version" yaml:"version" toml:"version"`
+	Definitions []*Definition `json:"definitions" yaml:"definitions" toml:"definitions"`
+	Caveats     []*Caveat     `json:"caveats,omitempty" yaml:"caveats,omitempty" toml:"caveats,omitempty"`
+	// Source is the schema DSL as a string, or a map of file name to DSL for multiple files
+	Source any `json:"source,omitempty" yaml:"source,omitempty" toml:"source,omitempty"`
+}
@@ -0,0 +1,290 @@
+package spice2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+	implv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// docCommentMetadata returns metadata with a DocComment message holding the raw value
+func docCommentMetadata(value []byte) *corev1.Metadata {
+	return &corev1.Metadata{MetadataMessage: []*anypb.Any{{
+		TypeUrl: "type.googleapis.com/" + string((&implv1.DocComment{}).ProtoReflect().Descriptor().FullName()),
+		Value:   value,
+	}}}
+}
+
+func TestGetMetadataCommentsMalformed(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *corev1.Metadata
+	}{
+		{"nil metadata", nil},
+		{"zero length value", docCommentMetadata(nil)},
+		{"one byte value", docCommentMetadata([]byte{0x0a})},
+		{"truncated length", docCommentMetadata([]byte{0x0a, 0x10, 'a'})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if comment := getMetadataComments(tt.metadata, Options{}); comment != "" {
+				t.Errorf("got comment %q, want none", comment)
+			}
+		})
+	}
+}
+
+func TestMapRelationTypeWildcard(t *testing.T) {
+	schema, err := Convert("definition user {}\ndefinition group {\n\trelation member: user\n}\ndefinition document {\n\trelation viewer: user | user:* | group#member\n}\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RelationType{
+		{Type: "user"},
+		{Type: "user", Relation: "*", Wildcard: true},
+		{Type: "group", Relation: "member"},
+	}
+	types := schema.Definitions[2].Relations[0].Types
+	if len(types) != len(want) {
+		t.Fatalf("got %d types, want %d", len(types), len(want))
+	}
+	for i, rt := range types {
+		if *rt != want[i] {
+			t.Errorf("type %d is %+v, want %+v", i, *rt, want[i])
+		}
+	}
+}
+
+func TestMapRelationTypeExpandWildcards(t *testing.T) {
+	schema, err := ConvertFrom("schema", "definition user {}\ndefinition document {\n\trelation viewer: user:*\n}\n", "", Options{ExpandWildcards: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt := schema.Definitions[1].Relations[0].Types[0]; rt.Relation != "" || !rt.Wildcard {
+		t.Errorf("got %+v, want wildcard without relation", *rt)
+	}
+}
+
+func TestRawComments(t *testing.T) {
+	source := "/**\n * first line\n *   indented second line\n * - third line bullet\n */\ndefinition user {}\n\n// line comment\ndefinition group {}\n"
+	schema, err := ConvertFrom("schema", source, "", Options{RawComments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "first line\n  indented second line\n- third line bullet"; schema.Definitions[0].Comment != want {
+		t.Errorf("got block comment %q, want %q", schema.Definitions[0].Comment, want)
+	}
+	if want := "line comment"; schema.Definitions[1].Comment != want {
+		t.Errorf("got line comment %q, want %q", schema.Definitions[1].Comment, want)
+	}
+}
+
+func TestLongComment(t *testing.T) {
+	// comments over 127 bytes have a multi byte length prefix in the DocComment message
+	long := strings.TrimSpace(strings.Repeat("a long comment ", 20))
+	value, err := (&implv1.DocComment{Comment: "// " + long}).MarshalVT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment := getMetadataComments(docCommentMetadata(value), Options{}); comment != long {
+		t.Errorf("got %q, want %q", comment, long)
+	}
+
+	schema, err := Convert("/** "+long+" */\ndefinition user {}\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Definitions[0].Comment != long {
+		t.Errorf("got %q from the compiled schema, want %q", schema.Definitions[0].Comment, long)
+	}
+}
+
+func TestNoComments(t *testing.T) {
+	source, err := os.ReadFile("testdata/comments.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ConvertFrom("schema", string(source), "", Options{NoComments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := WriteSchemaTo(schema, &out, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), `"comment"`) {
+		t.Errorf("got comment keys with NoComments: %s", out.String())
+	}
+}
+
+func TestMapRelationWithoutTypeInformation(t *testing.T) {
+	relation := mapRelation(&corev1.Relation{Name: "synthetic"}, Options{})
+	if relation.Types == nil || len(relation.Types) != 0 {
+		t.Errorf("got types %v, want an empty list", relation.Types)
+	}
+}
+
+func TestSplitNamespace(t *testing.T) {
+	tests := []struct {
+		fullname  string
+		name      string
+		namespace string
+	}{
+		{"document", "document", ""},
+		{"org/document", "document", "org"},
+		{"org/team/document", "document", "org/team"},
+	}
+	for _, tt := range tests {
+		name, namespace := splitNamespace(tt.fullname)
+		if name != tt.name || namespace != tt.namespace {
+			t.Errorf("%s split into name %q and namespace %q, want %q and %q", tt.fullname, name, namespace, tt.name, tt.namespace)
+		}
+	}
+}
+
+func TestMapPermissionWithoutRewrite(t *testing.T) {
+	var warnings []Warning
+	opts := Options{OnWarning: func(w Warning) { warnings = append(warnings, w) }}
+	p := mapPermission("document", &corev1.Relation{Name: "view"}, nil, opts)
+	if p.UserSet == nil || p.UserSet.Operation != "nil" {
+		t.Errorf("got user set %+v, want a nil operation leaf", p.UserSet)
+	}
+	if p.Expression != "nil" {
+		t.Errorf("got expression %q, want nil", p.Expression)
+	}
+	if len(warnings) != 1 || warnings[0].Check != "empty-rewrite" || warnings[0].Location != "document#view" {
+		t.Errorf("got warnings %v, want one empty-rewrite warning for document#view", warnings)
+	}
+}
+
+func TestIsAlias(t *testing.T) {
+	tests := []struct {
+		expression string
+		alias      bool
+	}{
+		{"viewer", true},
+		{"edit", true},
+		{"viewer + editor", false},
+		{"parent->view", false},
+		{"viewer - editor", false},
+		{"nil", false},
+	}
+	for _, tt := range tests {
+		source := "definition user {}\ndefinition document {\n\trelation parent: document\n\trelation viewer: user\n\trelation editor: user\n\tpermission edit = editor\n\tpermission view = " + tt.expression + "\n}\n"
+		schema, err := Convert(source, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := schema.Definitions[1].Permissions[1]; p.IsAlias != tt.alias {
+			t.Errorf("view = %s has isAlias %v, want %v", tt.expression, p.IsAlias, tt.alias)
+		}
+	}
+}
+
+func TestNoCaveats(t *testing.T) {
+	source, err := os.ReadFile("testdata/caveats.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ConvertFrom("schema", string(source), "", Options{NoCaveats: true, InlineCaveats: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := WriteSchemaTo(schema, &out, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var doc any
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var walk func(v any, path string)
+	walk = func(v any, path string) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, value := range v {
+				if strings.Contains(strings.ToLower(key), "caveat") {
+					t.Errorf("got caveat key %s.%s with NoCaveats", path, key)
+				}
+				walk(value, path+"."+key)
+			}
+		case []any:
+			for _, value := range v {
+				walk(value, path+"[]")
+			}
+		}
+	}
+	walk(doc, "")
+
+	// user with on_weekdays and user are the same type without caveats
+	if types := schema.Definitions[1].Relations[0].Types; len(types) != 2 {
+		t.Errorf("got %d types, want user and user:*", len(types))
+	}
+}
+
+func TestPermissionArrows(t *testing.T) {
+	source := `definition user {}
+definition org {
+	relation admin: user
+}
+definition folder {
+	relation parent: folder
+	relation org: org
+	relation viewer: user
+	permission view = viewer + (parent->view & org->admin)
+}
+`
+	schema, err := Convert(source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arrows := schema.Definitions[2].Permissions[0].Arrows
+	want := []Arrow{{Via: "parent", Target: "view"}, {Via: "org", Target: "admin"}}
+	if len(arrows) != len(want) {
+		t.Fatalf("got %d arrows, want %v", len(arrows), want)
+	}
+	for i, arrow := range arrows {
+		if *arrow != want[i] {
+			t.Errorf("arrow %d is %+v, want %+v", i, *arrow, want[i])
+		}
+	}
+}
+
+func TestRelationTypeCaveats(t *testing.T) {
+	source, err := os.ReadFile("../../example/caveats.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ConvertFrom("schema", string(source), "", Options{InlineCaveats: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewer := schema.Definitions[2].Relations[0]
+	want := []string{"user", "user with on_weekdays", "team#member with ip_allowlist", "team#member"}
+	if len(viewer.Types) != len(want) {
+		t.Fatalf("got %d types, want %v", len(viewer.Types), want)
+	}
+	for i, rt := range viewer.Types {
+		if relationTypeDSL(rt) != want[i] {
+			t.Errorf("type %d is %q, want %q", i, relationTypeDSL(rt), want[i])
+		}
+		if rt.Caveat != "" && (rt.CaveatDefinition == nil || rt.CaveatDefinition.Name != rt.Caveat) {
+			t.Errorf("type %q has caveat definition %+v, want %s", want[i], rt.CaveatDefinition, rt.Caveat)
+		}
+		if rt.Caveat == "" && rt.CaveatDefinition != nil {
+			t.Errorf("type %q without caveat has caveat definition %s", want[i], rt.CaveatDefinition.Name)
+		}
+	}
+
+	view := schema.Definitions[2].Permissions[0].UserSet.Children[0]
+	if strings.Join(view.Caveats, ",") != "on_weekdays,ip_allowlist" || !view.CaveatOptional {
+		t.Errorf("view references caveats %v with optional %v, want on_weekdays and ip_allowlist, optional", view.Caveats, view.CaveatOptional)
+	}
+}
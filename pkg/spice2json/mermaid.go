@@ -0,0 +1,77 @@
+package spice2json
+
+import (
+	"fmt"
+	"strings"
+)
+
+// writeMermaid renders a mermaid class diagram, with relations as fields, permissions as methods
+// and an arrow from each definition to its subject types. Wildcard subjects use a dotted arrow,
+// subject types of the definition itself and repeated arrows are left out.
+func writeMermaid(schema *Schema) []byte {
+	g := buildGraph(schema)
+
+	var b strings.Builder
+	b.WriteString("classDiagram\n")
+
+	for _, def := range g.Definitions {
+		var members []string
+		for _, n := range g.Nodes {
+			if n.Definition != def {
+				continue
+			}
+			switch n.Kind {
+			case "relation":
+				members = append(members, "+"+n.Label)
+			case "permission":
+				members = append(members, "+"+n.Label+"()")
+			}
+		}
+
+		class := def
+		if diagramID(def) != def {
+			class = fmt.Sprintf("%s[\"%s\"]", diagramID(def), def)
+		}
+		if len(members) == 0 {
+			fmt.Fprintf(&b, "  class %s\n", class)
+			continue
+		}
+		fmt.Fprintf(&b, "  class %s {\n", class)
+		for _, m := range members {
+			fmt.Fprintf(&b, "    %s\n", m)
+		}
+		b.WriteString("  }\n")
+	}
+
+	definitions := map[string]string{}
+	relations := map[string]string{}
+	for _, n := range g.Nodes {
+		definitions[n.ID] = n.Definition
+		relations[n.ID] = n.Label
+	}
+
+	seen := map[string]bool{}
+	for _, e := range g.Edges {
+		if e.Kind != "subject" {
+			continue
+		}
+		to, _, _ := strings.Cut(e.To, ":")
+		if to == definitions[e.From] {
+			continue
+		}
+		arrow := "-->"
+		label := relations[e.From]
+		if e.Wildcard {
+			arrow = "..>"
+			label += " *"
+		}
+
+		line := fmt.Sprintf("  %s %s %s : %s\n", diagramID(definitions[e.From]), arrow, diagramID(to), label)
+		if !seen[line] {
+			seen[line] = true
+			b.WriteString(line)
+		}
+	}
+
+	return []byte(b.String())
+}
@@ -0,0 +1,49 @@
+package spice2json
+
+import (
+	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+	implv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// MetadataExtractor is called with each metadata message of a definition, relation, permission
+// or caveat other than doc comments, e.g. the relation kind or custom messages registered with
+// the protobuf registry. The returned entries are added to the Metadata of the element.
+type MetadataExtractor func(message proto.Message) map[string]string
+
+// decodeMetadata returns the metadata messages of a type known to the protobuf registry,
+// skipping unknown and malformed ones
+func decodeMetadata(metadata *corev1.Metadata) []proto.Message {
+	var messages []proto.Message
+	for _, m := range metadata.GetMetadataMessage() {
+		message, err := m.UnmarshalNew()
+		if err != nil {
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// mapMetadata collects the entries of the metadata extractors, doc comments are mapped separately
+func mapMetadata(metadata *corev1.Metadata, opts Options) map[string]string {
+	if len(opts.MetadataExtractors) == 0 {
+		return nil
+	}
+
+	var entries map[string]string
+	for _, message := range decodeMetadata(metadata) {
+		if _, ok := message.(*implv1.DocComment); ok {
+			continue
+		}
+		for _, extract := range opts.MetadataExtractors {
+			for key, value := range extract(message) {
+				if entries == nil {
+					entries = map[string]string{}
+				}
+				entries[key] = value
+			}
+		}
+	}
+	return entries
+}
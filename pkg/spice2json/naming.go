@@ -0,0 +1,164 @@
+package spice2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// jsonField is a key and value of a jsonObject
+type jsonField struct {
+	Key   string
+	Value any
+}
+
+// jsonObject is a json object which keeps the order of its fields
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, err := marshalJSONValue(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSONValue(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// marshalJSONValue is json.Marshal without escaping <, > and &, like encodeJSON
+func marshalJSONValue(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// snakeCase converts a camelCase json key to snake_case, e.g. userSet to user_set
+func snakeCase(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// snakeCaseKeys returns v as it is encoded to json, with the keys of struct fields in
+// snake_case. Map keys, such as definition names, parameter names and metadata, are kept.
+func snakeCaseKeys(v any) any {
+	return snakeCaseValue(reflect.ValueOf(v))
+}
+
+func snakeCaseValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return snakeCaseValue(v.Elem())
+	case reflect.Struct:
+		return snakeCaseStruct(v, nil)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = snakeCaseValue(v.Index(i))
+		}
+		return values
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		object := make(jsonObject, len(keys))
+		for i, key := range keys {
+			object[i] = jsonField{Key: key.String(), Value: snakeCaseValue(v.MapIndex(key))}
+		}
+		return object
+	default:
+		return v.Interface()
+	}
+}
+
+// snakeCaseStruct adds the exported fields of the struct to object by their json tag, fields
+// of embedded structs without a tag are added as if they were fields of the outer struct
+func snakeCaseStruct(v reflect.Value, object jsonObject) jsonObject {
+	if object == nil {
+		object = jsonObject{}
+	}
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		value := v.Field(i)
+
+		if field.Anonymous && name == "" {
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				object = snakeCaseStruct(value, object)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if options == "omitempty" && isEmptyJSONValue(value) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		object = append(object, jsonField{Key: snakeCase(name), Value: snakeCaseValue(value)})
+	}
+	return object
+}
+
+// isEmptyJSONValue reports whether encoding/json leaves the value out with omitempty
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
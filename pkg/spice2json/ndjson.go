@@ -0,0 +1,80 @@
+package spice2json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+)
+
+// ndjsonDefinition is a definition line, with _type telling it apart from caveat lines
+type ndjsonDefinition struct {
+	Type string `json:"_type"`
+	*Definition
+}
+
+// ndjsonCaveat is a caveat line
+type ndjsonCaveat struct {
+	Type string `json:"_type"`
+	*Caveat
+}
+
+// writeNDJSON streams one compact json line per definition followed by one per caveat
+func writeNDJSON(schema *Schema, w io.Writer, opts Options) error {
+	lines := newNDJSONWriter(w, opts)
+	for _, def := range schema.Definitions {
+		if err := lines.definition(def); err != nil {
+			return err
+		}
+	}
+	return lines.caveats(schema.Caveats)
+}
+
+// WriteNDJSONFrom maps the compiled schema with StreamDefinitions and writes each definition
+// line as soon as it is mapped, followed by the caveat lines
+func WriteNDJSONFrom(schema *compiler.CompiledSchema, w io.Writer, opts Options) error {
+	caveats, err := mapCaveats(schema, opts)
+	if err != nil {
+		return err
+	}
+	lines := newNDJSONWriter(w, opts)
+	if err := streamDefinitions(schema, caveats, opts, lines.definition); err != nil {
+		return err
+	}
+	return lines.caveats(caveats)
+}
+
+type ndjsonWriter struct {
+	enc       *json.Encoder
+	snakeCase bool
+}
+
+func newNDJSONWriter(w io.Writer, opts Options) *ndjsonWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &ndjsonWriter{enc: enc, snakeCase: opts.SnakeCaseKeys}
+}
+
+func (n *ndjsonWriter) encode(line any) error {
+	if n.snakeCase {
+		line = snakeCaseKeys(line)
+	}
+	if err := n.enc.Encode(line); err != nil {
+		return fmt.Errorf("unable to write schema for export: %w", err)
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) definition(def *Definition) error {
+	return n.encode(ndjsonDefinition{Type: "definition", Definition: def})
+}
+
+func (n *ndjsonWriter) caveats(caveats []*Caveat) error {
+	for _, caveat := range caveats {
+		if err := n.encode(ndjsonCaveat{Type: "caveat", Caveat: caveat}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
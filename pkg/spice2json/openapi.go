@@ -0,0 +1,42 @@
+package spice2json
+
+import "io"
+
+// openAPIPermission describes checking one permission of a resource type
+type openAPIPermission struct {
+	ResourceType string   `json:"resourceType"`
+	Permission   string   `json:"permission"`
+	Description  string   `json:"description,omitempty"`
+	Expression   string   `json:"expression"`
+	SubjectTypes []string `json:"subjectTypes"`
+	Check        string   `json:"check"`
+}
+
+type openAPIFragment struct {
+	Components struct {
+		Permissions map[string]*openAPIPermission `json:"x-permissions"`
+	} `json:"components"`
+}
+
+// writeOpenAPIFragment writes an OpenAPI components fragment with an x-permissions entry per
+// type:permission, with the subject types from the same analysis as ResolveSubjects
+func writeOpenAPIFragment(schema *Schema, w io.Writer, opts Options) error {
+	definitions := definitionsByName(schema)
+
+	var fragment openAPIFragment
+	fragment.Components.Permissions = map[string]*openAPIPermission{}
+	for _, def := range schema.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, p := range def.Permissions {
+			fragment.Components.Permissions[memberID(defName, p.Name)] = &openAPIPermission{
+				ResourceType: defName,
+				Permission:   p.Name,
+				Description:  p.Comment,
+				Expression:   p.Expression,
+				SubjectTypes: resolvedSubjects(definitions, defName, p.Name),
+				Check:        "CheckPermission " + defName + ":{resourceId}#" + p.Name + " for {subjectType}:{subjectId}",
+			}
+		}
+	}
+	return writeDocument(fragment, w, "json", opts.Indent)
+}
@@ -0,0 +1,74 @@
+package spice2json
+
+// Options changes what is included when mapping a compiled schema and how WriteAs writes it,
+// the zero value gives the default output
+type Options struct {
+	// Positions adds the source position to definitions, relations and permissions
+	Positions bool
+
+	// RawComments keeps comments as written, only removing the opening and closing comment
+	// markers, so line breaks, indentation and bullet lists survive
+	RawComments bool
+
+	// InlineCaveats adds the full caveat definition to relation types next to the caveat name
+	InlineCaveats bool
+
+	// QualifiedSubjects adds the subject type of relation types as a single namespace/type#relation
+	// string, so consumers don't have to handle a missing namespace or relation
+	QualifiedSubjects bool
+
+	// ExpandWildcards leaves the relation of wildcard relation types empty instead of "*", so
+	// they only have the type and wildcard set
+	ExpandWildcards bool
+
+	// NoComments leaves all comments out without decoding the doc comment metadata
+	NoComments bool
+
+	// NoCaveats leaves out the caveats and the caveat names of relation types and user sets, for
+	// consumers that don't support caveats
+	NoCaveats bool
+
+	// CaveatAST adds the checked CEL expression of each caveat as protojson, which is a lot
+	// larger than the expression string
+	CaveatAST bool
+
+	// ParseAnnotations moves comment lines like "@owner: platform-team" or "@sensitive" out of
+	// the comment into the annotations of definitions, relations and permissions
+	ParseAnnotations bool
+
+	// RespectExclude leaves out definitions with an @exclude line in their doc comment,
+	// relations of other definitions still refer to them
+	RespectExclude bool
+
+	// MetadataExtractors map metadata messages other than doc comments into the Metadata of
+	// definitions, relations, permissions and caveats
+	MetadataExtractors []MetadataExtractor
+
+	// FaithfulTree keeps nil operands in permission user sets, as leaves with operation nil, so
+	// the user set is the compiled expression tree node for node
+	FaithfulTree bool
+
+	// Indent is the json indentation used by WriteAs, json is compact without it
+	Indent string
+
+	// SnakeCaseKeys makes WriteAs write the json and ndjson keys in snake_case, e.g. user_set
+	// instead of userSet. Other formats don't support it.
+	SnakeCaseKeys bool
+
+	// SkipUnknown leaves out relations that are neither a relation nor a permission, e.g. kinds
+	// added in newer SpiceDB versions, instead of failing the conversion
+	SkipUnknown bool
+
+	// Source is the schema DSL the compiled schema was compiled from. The compiled caveats
+	// don't keep the parameter order, without the source caveat parameters are sorted by name.
+	// ConvertFrom sets it.
+	Source string
+
+	// OnDefinition is called with each definition as soon as it is mapped, e.g. to count what
+	// the From writers wrote
+	OnDefinition func(*Definition)
+
+	// OnWarning is called for each relation left out by SkipUnknown, each permission without a
+	// user set rewrite and each caveat parameter type that can't be normalized
+	OnWarning func(Warning)
+}
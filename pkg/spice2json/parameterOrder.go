@@ -0,0 +1,82 @@
+package spice2json
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/schemadsl/lexer"
+)
+
+// sortedParameterNames is the fallback parameter order when the schema source is not available
+func sortedParameterNames(parameters map[string]string) []string {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// caveatParameterOrder scans the schema source and returns the parameter names of each caveat
+// in declaration order, keyed by the caveat name as written in the source. The compiled caveat
+// only holds its parameters in a map, so the order has to be recovered from the source text.
+func caveatParameterOrder(source string) map[string][]string {
+	lex := lexer.NewPeekableLexer(lexer.Lex(input.Source("schema"), source))
+	defer lex.Close()
+
+	orders := map[string][]string{}
+	var name strings.Builder
+	inCaveat := false
+	inParameters := false
+	expectParameter := false
+	for {
+		token := lex.NextToken()
+		switch token.Kind {
+		case lexer.TokenTypeEOF, lexer.TokenTypeError:
+			return orders
+		case lexer.TokenTypeKeyword:
+			if token.Value == "caveat" && !inCaveat {
+				inCaveat = true
+				name.Reset()
+			}
+		case lexer.TokenTypeIdentifier, lexer.TokenTypeDiv:
+			if inCaveat && !inParameters {
+				name.WriteString(token.Value)
+			} else if expectParameter && token.Kind == lexer.TokenTypeIdentifier {
+				orders[name.String()] = append(orders[name.String()], token.Value)
+				expectParameter = false
+			}
+		case lexer.TokenTypeLeftParen:
+			if inCaveat && !inParameters {
+				inParameters = true
+				expectParameter = true
+			}
+		case lexer.TokenTypeComma:
+			expectParameter = inParameters
+		case lexer.TokenTypeRightParen:
+			if inParameters {
+				inCaveat = false
+				inParameters = false
+				expectParameter = false
+			}
+		}
+	}
+}
+
+// orderCaveatParameters replaces the sorted fallback order with the declaration order from the
+// source. A caveat written without namespace has the default namespace in its compiled name,
+// so it is looked up by its name without namespace when the full name isn't in the source.
+func orderCaveatParameters(caveats []*Caveat, source string) {
+	orders := caveatParameterOrder(source)
+	for _, caveat := range caveats {
+		order, ok := orders[caveat.Name]
+		if !ok {
+			name, _ := splitNamespace(caveat.Name)
+			order, ok = orders[name]
+		}
+		if ok && len(order) == len(caveat.Parameters) {
+			caveat.ParameterOrder = order
+		}
+	}
+}
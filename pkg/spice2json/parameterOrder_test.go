@@ -0,0 +1,45 @@
+package spice2json
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCaveatParameterOrder(t *testing.T) {
+	source := `caveat ranked(zulu int, alpha string, mike list<string>) {
+	zulu > 0 && alpha in mike
+}
+
+caveat other/ranked(beta int, alpha int) {
+	beta > alpha
+}
+
+definition user {}
+`
+	for _, namespace := range []string{"", "app"} {
+		schema, err := Convert(source, namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{{"zulu", "alpha", "mike"}, {"beta", "alpha"}}
+		for i, caveat := range schema.Caveats {
+			if !slices.Equal(caveat.ParameterOrder, want[i]) {
+				t.Errorf("-n %q: %s has parameter order %v, want the declaration order %v", namespace, caveat.Name, caveat.ParameterOrder, want[i])
+			}
+		}
+	}
+}
+
+func TestCaveatParameterOrderWithoutSource(t *testing.T) {
+	compiled, err := Compile("schema", "caveat ranked(zulu int, alpha string, mike int) {\n\tzulu > mike\n}\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := MapSchema(compiled, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order := schema.Caveats[0].ParameterOrder; !slices.Equal(order, []string{"alpha", "mike", "zulu"}) {
+		t.Errorf("got parameter order %v, want sorted by name without Options.Source", order)
+	}
+}
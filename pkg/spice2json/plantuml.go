@@ -0,0 +1,82 @@
+package spice2json
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// writePlantUML renders a plantuml class diagram, with relations as fields, permissions as methods,
+// an arrow from each definition to its subject types and a dotted arrow for each permission
+// reaching through a relation. Caveated relations get the caveat names as stereotype.
+func writePlantUML(schema *Schema) []byte {
+	g := buildGraph(schema)
+
+	caveats := map[string][]string{}
+	for _, e := range g.Edges {
+		if e.Kind == "subject" && e.Caveat != "" && !slices.Contains(caveats[e.From], e.Caveat) {
+			caveats[e.From] = append(caveats[e.From], e.Caveat)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("@startuml\n")
+
+	for _, def := range g.Definitions {
+		class := def
+		if diagramID(def) != def {
+			class = fmt.Sprintf("\"%s\" as %s", def, diagramID(def))
+		}
+		fmt.Fprintf(&b, "class %s {\n", class)
+		for _, n := range g.Nodes {
+			if n.Definition != def {
+				continue
+			}
+			switch n.Kind {
+			case "relation":
+				member := "  +" + n.Label
+				if len(caveats[n.ID]) > 0 {
+					member += " <<" + strings.Join(caveats[n.ID], ", ") + ">>"
+				}
+				b.WriteString(member + "\n")
+			case "permission":
+				fmt.Fprintf(&b, "  +%s()\n", n.Label)
+			}
+		}
+		b.WriteString("}\n")
+	}
+
+	definitions := map[string]string{}
+	labels := map[string]string{}
+	for _, n := range g.Nodes {
+		definitions[n.ID] = n.Definition
+		labels[n.ID] = n.Label
+	}
+
+	seen := map[string]bool{}
+	for _, e := range g.Edges {
+		to, member, _ := strings.Cut(e.To, ":")
+		var line string
+		switch e.Kind {
+		case "subject":
+			label := labels[e.From]
+			if e.Wildcard {
+				label += " *"
+			}
+			line = fmt.Sprintf("%s --> %s : %s\n", diagramID(definitions[e.From]), diagramID(to), label)
+		case "arrow":
+			label := fmt.Sprintf("%s = %s->%s", labels[e.From], e.Label, member)
+			line = fmt.Sprintf("%s ..> %s : %s\n", diagramID(definitions[e.From]), diagramID(to), label)
+		default:
+			continue
+		}
+
+		if !seen[line] {
+			seen[line] = true
+			b.WriteString(line)
+		}
+	}
+
+	b.WriteString("@enduml\n")
+	return []byte(b.String())
+}
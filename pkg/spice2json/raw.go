@@ -0,0 +1,62 @@
+package spice2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// rawSchema holds the compiled definition protos as protojson
+type rawSchema struct {
+	ObjectDefinitions []json.RawMessage `json:"objectDefinitions"`
+	CaveatDefinitions []json.RawMessage `json:"caveatDefinitions,omitempty"`
+}
+
+// WriteRawTo compiles the schema and writes the object and caveat definition protos as protojson
+// instead of the mapped Schema, for comparing the mapping with what the compiler produced.
+// Json is indented like WriteSchemaIndentTo.
+func WriteRawTo(w io.Writer, sourceName string, schemaSource string, defaultNamespace string, indent string) error {
+	if err := ValidateIndent(indent); err != nil {
+		return err
+	}
+
+	def, err := Compile(sourceName, schemaSource, defaultNamespace)
+	if err != nil {
+		return err
+	}
+
+	raw := rawSchema{ObjectDefinitions: []json.RawMessage{}}
+	for _, o := range def.ObjectDefinitions {
+		data, err := protojson.Marshal(o)
+		if err != nil {
+			return fmt.Errorf("failed to export %q: %w", o.Name, err)
+		}
+		raw.ObjectDefinitions = append(raw.ObjectDefinitions, data)
+	}
+	for _, caveat := range def.CaveatDefinitions {
+		data, err := protojson.Marshal(caveat)
+		if err != nil {
+			return fmt.Errorf("failed to export %q: %w", caveat.Name, err)
+		}
+		raw.CaveatDefinitions = append(raw.CaveatDefinitions, data)
+	}
+
+	// protojson output isn't stable in its spacing, so it's compacted before indenting
+	for _, list := range [][]json.RawMessage{raw.ObjectDefinitions, raw.CaveatDefinitions} {
+		for i, data := range list {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, data); err != nil {
+				return err
+			}
+			list[i] = buf.Bytes()
+		}
+	}
+
+	if err := encodeJSON(&trimFinalNewline{w: w}, raw, indent); err != nil {
+		return fmt.Errorf("unable to write schema for export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package spice2json
+
+// References counts for each definition, by namespace/name, how many relation types of the
+// other definitions have it as subject type. Definitions nothing refers to are included with 0.
+func (s *Schema) References() map[string]int {
+	references := map[string]int{}
+	for _, def := range s.Definitions {
+		references[qualifiedName(def.Name, def.Namespace)] = 0
+	}
+
+	for _, def := range s.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, r := range def.Relations {
+			for _, t := range r.Types {
+				subject := qualifiedName(t.Type, t.Namespace)
+				if _, ok := references[subject]; ok && subject != defName {
+					references[subject]++
+				}
+			}
+		}
+	}
+	return references
+}
+
+// MarkOrphans sets Orphan on the definitions that no relation in the schema has as subject
+// type. Unlike References a definition referring to itself, e.g. folder#parent: folder, counts,
+// so it isn't an orphan.
+func (s *Schema) MarkOrphans() {
+	used := map[string]bool{}
+	for _, def := range s.Definitions {
+		for _, r := range def.Relations {
+			for _, t := range r.Types {
+				used[qualifiedName(t.Type, t.Namespace)] = true
+			}
+		}
+	}
+	for _, def := range s.Definitions {
+		def.Orphan = !used[qualifiedName(def.Name, def.Namespace)]
+	}
+}
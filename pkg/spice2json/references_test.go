@@ -0,0 +1,42 @@
+package spice2json
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMarkOrphans(t *testing.T) {
+	source, err := os.ReadFile("../../example/orphan.zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := Convert(string(source), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.MarkOrphans()
+
+	want := map[string]bool{
+		"user":          false,
+		"folder":        false,
+		"document":      false,
+		"legacy_report": true,
+		// only the subject type of its own parent relation
+		"team": false,
+	}
+	for _, def := range schema.Definitions {
+		if def.Orphan != want[def.Name] {
+			t.Errorf("%s has orphan %v, want %v", def.Name, def.Orphan, want[def.Name])
+		}
+	}
+}
+
+func TestReferencesLeavesOutSelfReferences(t *testing.T) {
+	schema, err := Convert("definition folder {\n\trelation parent: folder\n}\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.References()["folder"]; got != 0 {
+		t.Errorf("folder has %d references, want 0 without its own parent relation", got)
+	}
+}
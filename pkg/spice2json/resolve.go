@@ -0,0 +1,111 @@
+package spice2json
+
+import "sort"
+
+// ResolveSubjects sets the resolved subjects of each permission, the subject types reached by
+// following its relations, permissions and arrows down to relations with direct subjects.
+// Subject relations such as group#member are followed as well, wildcards resolve to their type.
+// Intersections and exclusions are treated like unions, so the result may include types that
+// can never be granted the permission.
+func (s *Schema) ResolveSubjects() {
+	definitions := definitionsByName(s)
+	for _, def := range s.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, p := range def.Permissions {
+			p.ResolvedSubjects = resolvedSubjects(definitions, defName, p.Name)
+		}
+	}
+}
+
+func definitionsByName(s *Schema) map[string]*Definition {
+	definitions := map[string]*Definition{}
+	for _, def := range s.Definitions {
+		definitions[qualifiedName(def.Name, def.Namespace)] = def
+	}
+	return definitions
+}
+
+// resolvedSubjects returns the sorted subject types of the relation or permission
+func resolvedSubjects(definitions map[string]*Definition, defName string, member string) []string {
+	subjects := map[string]bool{}
+	resolveMember(definitions, defName, member, map[string]bool{}, subjects)
+	names := make([]string, 0, len(subjects))
+	for subject := range subjects {
+		names = append(names, subject)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveMember adds the subject types of the relation or permission to subjects, visited
+// holds the definition:member ids already resolved to stop at recursive schemas
+func resolveMember(definitions map[string]*Definition, defName string, member string, visited map[string]bool, subjects map[string]bool) {
+	id := memberID(defName, member)
+	if visited[id] {
+		return
+	}
+	visited[id] = true
+
+	def, ok := definitions[defName]
+	if !ok {
+		return
+	}
+
+	for _, r := range def.Relations {
+		if r.Name != member {
+			continue
+		}
+		for _, t := range r.Types {
+			subject := qualifiedName(t.Type, t.Namespace)
+			if t.Relation == "" || t.Wildcard || t.Relation == "*" {
+				subjects[subject] = true
+				continue
+			}
+			resolveMember(definitions, subject, t.Relation, visited, subjects)
+		}
+		return
+	}
+
+	for _, p := range def.Permissions {
+		if p.Name != member {
+			continue
+		}
+		walkUserSet(p.UserSet, func(set *UserSet) {
+			if set.Relation == "" {
+				return
+			}
+			if set.Permission == "" {
+				resolveMember(definitions, defName, set.Relation, visited, subjects)
+				return
+			}
+			for _, r := range def.Relations {
+				if r.Name != set.Relation {
+					continue
+				}
+				for _, t := range r.Types {
+					resolveMember(definitions, qualifiedName(t.Type, t.Namespace), set.Permission, visited, subjects)
+				}
+			}
+		})
+		return
+	}
+}
+
+// SubjectIndex returns the sorted definition:permission ids of every permission the subject type,
+// by namespace/name, can be granted. It is the inverse of ResolveSubjects and has the same caveats.
+func (s *Schema) SubjectIndex(subject string) []string {
+	definitions := definitionsByName(s)
+	permissions := []string{}
+	for _, def := range s.Definitions {
+		defName := qualifiedName(def.Name, def.Namespace)
+		for _, p := range def.Permissions {
+			subjects := map[string]bool{}
+			resolveMember(definitions, defName, p.Name, map[string]bool{}, subjects)
+			if subjects[subject] {
+				permissions = append(permissions, memberID(defName, p.Name))
+			}
+		}
+	}
+	sort.Strings(permissions)
+	return permissions
+}
@@ -0,0 +1,49 @@
+package spice2json
+
+import "sort"
+
+// Sort orders definitions by namespace and name, relations and permissions by name,
+// relation types by type and relation, and caveats by name, so output is reproducible
+func (s *Schema) Sort() {
+	sortDefinitions(s.Definitions)
+
+	for _, def := range s.Definitions {
+		sort.SliceStable(def.Relations, func(i, j int) bool {
+			return def.Relations[i].Name < def.Relations[j].Name
+		})
+		sort.SliceStable(def.Permissions, func(i, j int) bool {
+			return def.Permissions[i].Name < def.Permissions[j].Name
+		})
+
+		for _, r := range def.Relations {
+			sort.SliceStable(r.Types, func(i, j int) bool {
+				a, b := r.Types[i], r.Types[j]
+				if a.Namespace != b.Namespace {
+					return a.Namespace < b.Namespace
+				}
+				if a.Type != b.Type {
+					return a.Type < b.Type
+				}
+				return a.Relation < b.Relation
+			})
+		}
+	}
+
+	sortCaveats(s.Caveats)
+}
+
+func sortDefinitions(definitions []*Definition) {
+	sort.SliceStable(definitions, func(i, j int) bool {
+		a, b := definitions[i], definitions[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+}
+
+func sortCaveats(caveats []*Caveat) {
+	sort.SliceStable(caveats, func(i, j int) bool {
+		return caveats[i].Name < caveats[j].Name
+	})
+}
@@ -0,0 +1,263 @@
+// Package spice2json converts a SpiceDB schema into a simplified representation
+// suitable for code generation in other languages.
+package spice2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/authzed/spicedb/pkg/namespace"
+	implv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputVersion identifies the layout of the output, it is bumped when the shape changes
+// in a way existing consumers can't parse
+const OutputVersion = "2"
+
+// OutputSchemaURL is the JSON Schema describing the output, published in the schema directory
+const OutputSchemaURL = "https://raw.githubusercontent.com/alsbury/spice2json/main/schema/spice2json.schema.json"
+
+// Convert compiles the schema DSL and returns the mapped Schema. The default namespace is only
+// applied to definitions, caveats and type references written without a namespace/ prefix.
+func Convert(schemaSource string, defaultNamespace string) (*Schema, error) {
+	return ConvertFrom("schema", schemaSource, defaultNamespace, Options{})
+}
+
+// ConvertFrom is Convert with a source name, e.g. the file name, which is
+// included in compiler errors along with the line and column, and mapping options.
+// Options.Source is set to the schema source.
+func ConvertFrom(sourceName string, schemaSource string, defaultNamespace string, opts Options) (*Schema, error) {
+	def, err := Compile(sourceName, schemaSource, defaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Source = schemaSource
+	return MapSchema(def, opts)
+}
+
+// Compile compiles the schema DSL like ConvertFrom, for MapSchema, StreamDefinitions and the
+// From writers. Pass the source as Options.Source to keep the caveat parameter order.
+func Compile(sourceName string, schemaSource string, defaultNamespace string) (*compiler.CompiledSchema, error) {
+	in := compiler.InputSchema{
+		Source:       input.Source(sourceName),
+		SchemaString: schemaSource,
+	}
+	return compiler.Compile(in, compiler.ObjectTypePrefix(defaultNamespace))
+}
+
+// MapSchema Portions of this code were pulled from https://github.com/oviva-ag/spicedb
+func MapSchema(schema *compiler.CompiledSchema, opts Options) (*Schema, error) {
+	caveats, err := mapCaveats(schema, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var definitions []*Definition
+	err = streamDefinitions(schema, caveats, opts, func(def *Definition) error {
+		definitions = append(definitions, def)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema{
+		JSONSchema:  OutputSchemaURL,
+		Version:     OutputVersion,
+		Definitions: definitions,
+		Caveats:     caveats,
+	}, nil
+}
+
+func mapCaveats(schema *compiler.CompiledSchema, opts Options) ([]*Caveat, error) {
+	var caveats []*Caveat
+	for _, caveat := range schema.CaveatDefinitions {
+		if opts.NoCaveats {
+			break
+		}
+		o, err := mapCaveat(caveat, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %q: %w", caveat.Name, err)
+		}
+		caveats = append(caveats, o)
+	}
+	if opts.Source != "" {
+		orderCaveatParameters(caveats, opts.Source)
+	}
+	return caveats, nil
+}
+
+// memberKinds returns relation or permission for each definition:member of the compiled schema,
+// leaving out the definitions MapSchema leaves out
+func memberKinds(schema *compiler.CompiledSchema, opts Options) map[string]string {
+	kinds := map[string]string{}
+	for _, def := range schema.ObjectDefinitions {
+		if opts.RespectExclude && isExcluded(def.GetMetadata()) {
+			continue
+		}
+		for _, r := range def.Relation {
+			switch namespace.GetRelationKind(r) {
+			case implv1.RelationMetadata_RELATION:
+				kinds[memberID(def.Name, r.Name)] = "relation"
+			case implv1.RelationMetadata_PERMISSION:
+				kinds[memberID(def.Name, r.Name)] = "permission"
+			}
+		}
+	}
+	return kinds
+}
+
+// annotateArrowKinds sets the kind of each arrow by looking up its permission on the subject
+// types of the arrow's relation
+func annotateArrowKinds(def *Definition, kinds map[string]string) {
+	relations := map[string]*Relation{}
+	for _, r := range def.Relations {
+		relations[r.Name] = r
+	}
+	for _, p := range def.Permissions {
+		walkUserSet(p.UserSet, func(set *UserSet) {
+			tupleset, ok := relations[set.Relation]
+			if set.Permission == "" || !ok {
+				return
+			}
+			for _, t := range tupleset.Types {
+				kind := kinds[memberID(qualifiedName(t.Type, t.Namespace), set.Permission)]
+				if kind == "" {
+					continue
+				}
+				if set.Kind != "" && set.Kind != kind {
+					kind = "mixed"
+				}
+				set.Kind = kind
+			}
+		})
+	}
+}
+
+// inlineCaveats sets the full caveat on each relation type requiring one
+func inlineCaveats(def *Definition, caveats map[string]*Caveat) {
+	for _, r := range def.Relations {
+		for _, t := range r.Types {
+			if t.Caveat != "" {
+				t.CaveatDefinition = caveats[t.Caveat]
+			}
+		}
+	}
+}
+
+// WriteSchemaTo serializes the schema in the given format, json, ndjson, yaml, toml, dot,
+// mermaid, plantuml, csv, openapi-fragment or edges. In toml the nested user set children
+// become arrays of tables, csv only has the relations and ndjson has one line per definition
+// and caveat. Json is written compact.
+func WriteSchemaTo(schema *Schema, w io.Writer, format string) error {
+	return WriteSchemaIndentTo(schema, w, format, "")
+}
+
+// WriteSchemaIndentTo is WriteSchemaTo with json indented by indent, which may only contain
+// spaces and tabs. Json is encoded straight into w rather than buffered and re-indented.
+func WriteSchemaIndentTo(schema *Schema, w io.Writer, format string, indent string) error {
+	return WriteAs(schema, format, w, Options{Indent: indent})
+}
+
+// writeDocument serializes doc as json, yaml or toml
+func writeDocument(doc any, w io.Writer, format string, indent string) error {
+	if format == "json" {
+		if err := ValidateIndent(indent); err != nil {
+			return err
+		}
+		if err := encodeJSON(&trimFinalNewline{w: w}, doc, indent); err != nil {
+			return fmt.Errorf("unable to write schema for export: %w", err)
+		}
+		return nil
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case "yaml":
+		data, err = yaml.Marshal(doc)
+	case "toml":
+		var buf bytes.Buffer
+		err = toml.NewEncoder(&buf).Encode(doc)
+		data = buf.Bytes()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to serialize schema for export: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("unable to write schema for export: %w", err)
+	}
+	return nil
+}
+
+// encodeJSON writes v without escaping <, > and &, which appear in permission expressions
+func encodeJSON(w io.Writer, v any, indent string) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	return enc.Encode(v)
+}
+
+// trimFinalNewline drops the newline json.Encoder adds after the document
+type trimFinalNewline struct {
+	w       io.Writer
+	pending bool
+}
+
+func (t *trimFinalNewline) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if t.pending {
+		if _, err := t.w.Write([]byte("\n")); err != nil {
+			return 0, err
+		}
+		t.pending = false
+	}
+	data := p
+	if data[len(data)-1] == '\n' {
+		data = data[:len(data)-1]
+		t.pending = true
+	}
+	if _, err := t.w.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ValidateIndent checks that the json indent only contains spaces and tabs
+func ValidateIndent(indent string) error {
+	if strings.Trim(indent, " \t") != "" {
+		return fmt.Errorf("indent %q may only contain spaces and tabs", indent)
+	}
+	return nil
+}
+
+// PrettyString https://gosamples.dev/pretty-print-json/
+func PrettyString(str string) (string, error) {
+	return IndentString(str, "  ")
+}
+
+// IndentString is PrettyString with a custom indent, which may only contain spaces and tabs
+func IndentString(str string, indent string) (string, error) {
+	if err := ValidateIndent(indent); err != nil {
+		return "", err
+	}
+
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, []byte(str), "", indent); err != nil {
+		return "", err
+	}
+	return prettyJSON.String(), nil
+}
@@ -0,0 +1,183 @@
+package spice2json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// syntheticSchema returns a schema DSL with n document definitions, each with a parent
+// arrow to the previous one, and with a permission nested depth levels deep unless it is 0
+func syntheticSchema(n int, depth int) string {
+	var b strings.Builder
+	b.WriteString("definition user {}\n")
+	b.WriteString("definition doc0 {\n\trelation viewer: user\n\tpermission view = viewer\n}\n")
+	for i := 1; i < n; i++ {
+		fmt.Fprintf(&b, "/** document %d */\ndefinition doc%d {\n", i, i)
+		fmt.Fprintf(&b, "\trelation parent: doc%d\n", i-1)
+		b.WriteString("\trelation viewer: user | user:*\n")
+		b.WriteString("\trelation editor: user\n")
+		b.WriteString("\tpermission edit = editor\n")
+		b.WriteString("\tpermission view = viewer + edit + parent->view\n")
+		if depth > 0 {
+			fmt.Fprintf(&b, "\tpermission nested = %s\n", nestedRewrite(depth))
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// nestedRewrite returns a permission expression nested depth levels deep, cycling through
+// union, intersection and exclusion
+func nestedRewrite(depth int) string {
+	operators := []string{"+", "&", "-"}
+	leaves := []string{"editor", "parent->view", "edit", "viewer"}
+	expression := "viewer"
+	for i := 0; i < depth; i++ {
+		expression = fmt.Sprintf("(%s %s %s)", expression, operators[i%len(operators)], leaves[i%len(leaves)])
+	}
+	return expression
+}
+
+func benchmarkSchema(b *testing.B) *Schema {
+	b.Helper()
+	schema, err := Convert(syntheticSchema(5000, 0), "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return schema
+}
+
+// BenchmarkConvert measures the whole compile and map path
+func BenchmarkConvert(b *testing.B) {
+	source := syntheticSchema(2000, 12)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Convert(source, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMapUserSet measures only the recursive mapping of the compiled rewrite trees
+func BenchmarkMapUserSet(b *testing.B) {
+	compiled, err := Compile("schema", syntheticSchema(2000, 12), "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	type rewrite struct {
+		userset *corev1.UsersetRewrite
+		caveats map[string]relationCaveat
+	}
+	var rewrites []rewrite
+	for _, def := range compiled.ObjectDefinitions {
+		caveats := relationCaveats(def)
+		for _, relation := range def.Relation {
+			if relation.GetUsersetRewrite() != nil {
+				rewrites = append(rewrites, rewrite{relation.GetUsersetRewrite(), caveats})
+			}
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, r := range rewrites {
+			var arrows []*Arrow
+			mapUserSet(r.userset, r.caveats, &arrows, Options{})
+		}
+	}
+}
+
+// BenchmarkWriteSchemaIndentTo encodes indented json straight into the writer
+func BenchmarkWriteSchemaIndentTo(b *testing.B) {
+	schema := benchmarkSchema(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := WriteSchemaIndentTo(schema, io.Discard, "json", "  "); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkIndentString is the buffered path WriteSchemaIndentTo replaced, marshal into a
+// string and re-indent it
+func BenchmarkIndentString(b *testing.B) {
+	schema := benchmarkSchema(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := json.Marshal(schema)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pretty, err := IndentString(string(data), "  ")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.WriteString(io.Discard, pretty); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestConvertDefaultNamespace(t *testing.T) {
+	source := "definition user {}\ndefinition billing/account {\n\trelation owner: user\n}\ndefinition org/team/document {\n\trelation account: billing/account\n}\n"
+	schema, err := Convert(source, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"user": "myapp", "account": "billing", "document": "org/team"}
+	for _, def := range schema.Definitions {
+		if def.Namespace != want[def.Name] {
+			t.Errorf("%s has namespace %q, want %q", def.Name, def.Namespace, want[def.Name])
+		}
+	}
+
+	// relation types keep the full name, unlike definitions
+	owner := schema.Definitions[1].Relations[0].Types[0]
+	if owner.Type != "myapp/user" || owner.Namespace != "myapp" {
+		t.Errorf("billing/account#owner has type %s in namespace %q, want myapp/user in myapp", owner.Type, owner.Namespace)
+	}
+	account := schema.Definitions[2].Relations[0].Types[0]
+	if account.Type != "billing/account" || account.Namespace != "billing" {
+		t.Errorf("org/team/document#account has type %s in namespace %q, want billing/account in billing", account.Type, account.Namespace)
+	}
+}
+
+func TestArrowKinds(t *testing.T) {
+	source := `definition user {}
+definition folder {
+	relation viewer: user
+	permission view = viewer
+}
+definition team {
+	relation view: user
+}
+definition document {
+	relation folder: folder
+	relation team: team
+	relation any: folder | team
+	permission to_permission = folder->view
+	permission to_relation = team->view
+	permission to_both = any->view
+}
+`
+	schema, err := Convert(source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"to_permission": "permission", "to_relation": "relation", "to_both": "mixed"}
+	for _, p := range schema.Definitions[3].Permissions {
+		arrow := p.UserSet.Children[0]
+		if arrow.Kind != want[p.Name] {
+			t.Errorf("%s has arrow kind %q, want %q", p.Name, arrow.Kind, want[p.Name])
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package spice2json
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+)
+
+// ManifestFile is one generated file of the split output
+type ManifestFile struct {
+	File       string   `json:"file"`
+	Definition string   `json:"definition,omitempty"`
+	Caveats    []string `json:"caveats,omitempty"`
+}
+
+// Manifest lists the files written by WriteSplit
+type Manifest struct {
+	Version string          `json:"version"`
+	Files   []*ManifestFile `json:"files"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// SplitFileName is the file name of a definition in the split output, namespace_name.json
+// with anything but letters, digits, _, . and - replaced by _
+func SplitFileName(def *Definition) string {
+	name := def.Name
+	if def.Namespace != "" {
+		name = def.Namespace + "_" + def.Name
+	}
+	return unsafeFileChars.ReplaceAllString(name, "_") + ".json"
+}
+
+// WriteSplit writes each definition as json to its own file in dir, the caveats to caveats.json
+// and a manifest.json listing the generated files. Json is indented like WriteSchemaIndentTo.
+func WriteSplit(schema *Schema, dir string, indent string) (*Manifest, error) {
+	if err := ValidateIndent(indent); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return writeSplit(schema, indent, func(name string) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(dir, name))
+	})
+}
+
+// WriteSplitZip is WriteSplit into a zip archive, each file is streamed into the archive as
+// it is encoded
+func WriteSplitZip(schema *Schema, w io.Writer, indent string) (*Manifest, error) {
+	if err := ValidateIndent(indent); err != nil {
+		return nil, err
+	}
+	archive := zip.NewWriter(w)
+	manifest, err := writeSplit(schema, indent, func(name string) (io.WriteCloser, error) {
+		entry, err := archive.Create(name)
+		return zipEntry{entry}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, archive.Close()
+}
+
+// zipEntry is a zip archive entry, which is complete once the next entry is created
+type zipEntry struct {
+	io.Writer
+}
+
+func (zipEntry) Close() error {
+	return nil
+}
+
+// WriteSplitFrom is WriteSplit for a compiled schema, each definition is mapped with
+// StreamDefinitions and written to its file before the next one is mapped. Json is indented
+// with opts.Indent.
+func WriteSplitFrom(schema *compiler.CompiledSchema, dir string, opts Options) (*Manifest, error) {
+	if err := ValidateIndent(opts.Indent); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	caveats, err := mapCaveats(schema, opts)
+	if err != nil {
+		return nil, err
+	}
+	split := newSplitWriter(opts.Indent, func(name string) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(dir, name))
+	})
+	if err := streamDefinitions(schema, caveats, opts, split.definition); err != nil {
+		return nil, err
+	}
+	return split.finish(caveats)
+}
+
+// writeSplit writes the split output through create, which opens a file by name
+func writeSplit(schema *Schema, indent string, create func(string) (io.WriteCloser, error)) (*Manifest, error) {
+	split := newSplitWriter(indent, create)
+	for _, def := range schema.Definitions {
+		if err := split.definition(def); err != nil {
+			return nil, err
+		}
+	}
+	return split.finish(schema.Caveats)
+}
+
+// splitWriter writes each definition file as it is given and keeps the manifest
+type splitWriter struct {
+	indent   string
+	create   func(string) (io.WriteCloser, error)
+	manifest *Manifest
+	files    map[string]string
+}
+
+func newSplitWriter(indent string, create func(string) (io.WriteCloser, error)) *splitWriter {
+	return &splitWriter{
+		indent:   indent,
+		create:   create,
+		manifest: &Manifest{Version: OutputVersion},
+		files:    map[string]string{"manifest.json": "the manifest", "caveats.json": "the caveats"},
+	}
+}
+
+func (s *splitWriter) definition(def *Definition) error {
+	name := qualifiedName(def.Name, def.Namespace)
+	file := SplitFileName(def)
+	if other, ok := s.files[file]; ok {
+		return fmt.Errorf("definition %q and %s both write to %s", name, other, file)
+	}
+	s.files[file] = fmt.Sprintf("definition %q", name)
+	s.manifest.Files = append(s.manifest.Files, &ManifestFile{File: file, Definition: name})
+	return writeSplitFile(s.create, file, def, s.indent)
+}
+
+// finish writes the caveats and the manifest
+func (s *splitWriter) finish(caveats []*Caveat) (*Manifest, error) {
+	if len(caveats) > 0 {
+		file := &ManifestFile{File: "caveats.json"}
+		for _, caveat := range caveats {
+			file.Caveats = append(file.Caveats, caveat.Name)
+		}
+		s.manifest.Files = append(s.manifest.Files, file)
+		if err := writeSplitFile(s.create, file.File, caveats, s.indent); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeSplitFile(s.create, "manifest.json", s.manifest, s.indent); err != nil {
+		return nil, err
+	}
+	return s.manifest, nil
+}
+
+func writeSplitFile(create func(string) (io.WriteCloser, error), name string, doc any, indent string) error {
+	w, err := create(name)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", name, err)
+	}
+	err = encodeJSON(w, doc, indent)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", name, err)
+	}
+	return nil
+}
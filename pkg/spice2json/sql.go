@@ -0,0 +1,109 @@
+package spice2json
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlTables creates the tables written by the sql format, in postgres syntax
+const sqlTables = `CREATE TABLE IF NOT EXISTS definitions (
+	name text PRIMARY KEY,
+	namespace text,
+	comment text
+);
+CREATE TABLE IF NOT EXISTS relations (
+	definition text NOT NULL,
+	name text NOT NULL,
+	position integer NOT NULL,
+	comment text,
+	PRIMARY KEY (definition, name)
+);
+CREATE TABLE IF NOT EXISTS relation_types (
+	definition text NOT NULL,
+	relation text NOT NULL,
+	subject_type text NOT NULL,
+	subject_relation text,
+	wildcard boolean NOT NULL,
+	caveat text
+);
+CREATE TABLE IF NOT EXISTS permissions (
+	definition text NOT NULL,
+	name text NOT NULL,
+	position integer NOT NULL,
+	expression text NOT NULL,
+	comment text,
+	PRIMARY KEY (definition, name)
+);
+CREATE TABLE IF NOT EXISTS caveats (
+	name text PRIMARY KEY,
+	expression text,
+	comment text
+);
+CREATE TABLE IF NOT EXISTS caveat_parameters (
+	caveat text NOT NULL,
+	name text NOT NULL,
+	type text NOT NULL,
+	position integer NOT NULL,
+	PRIMARY KEY (caveat, name)
+);
+`
+
+// sqlString quotes s as a postgres string literal, empty strings are NULL
+func sqlString(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func writeSQLInsert(b *strings.Builder, table string, values ...string) {
+	fmt.Fprintf(b, "INSERT INTO %s VALUES (%s);\n", table, strings.Join(values, ", "))
+}
+
+// writeSQL writes postgres CREATE TABLE statements followed by one INSERT per definition,
+// relation, relation type, permission, caveat and caveat parameter, in a single transaction.
+// Definitions are referenced by namespace/name, wildcards have * as subject relation.
+func writeSQL(schema *Schema) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("BEGIN;\n\n")
+	b.WriteString(sqlTables)
+	b.WriteString("\n")
+
+	for _, def := range schema.Definitions {
+		name := qualifiedName(def.Name, def.Namespace)
+		writeSQLInsert(&b, "definitions", sqlString(name), sqlString(def.Namespace), sqlString(def.Comment))
+		for _, r := range def.Relations {
+			writeSQLInsert(&b, "relations", sqlString(name), sqlString(r.Name), fmt.Sprint(r.Index), sqlString(r.Comment))
+			for _, t := range r.Types {
+				relation := t.Relation
+				if t.Wildcard {
+					relation = "*"
+				}
+				writeSQLInsert(&b, "relation_types", sqlString(name), sqlString(r.Name), sqlString(qualifiedName(t.Type, t.Namespace)),
+					sqlString(relation), fmt.Sprint(t.Wildcard), sqlString(t.Caveat))
+			}
+		}
+		for _, p := range def.Permissions {
+			writeSQLInsert(&b, "permissions", sqlString(name), sqlString(p.Name), fmt.Sprint(p.Index),
+				sqlString(userSetExpression(p.UserSet)), sqlString(p.Comment))
+		}
+	}
+
+	for _, caveat := range schema.Caveats {
+		writeSQLInsert(&b, "caveats", sqlString(caveat.Name), sqlString(caveat.Expression), sqlString(caveat.Comment))
+		order := caveat.ParameterOrder
+		if len(order) != len(caveat.Parameters) {
+			order = sortedParameterNames(caveat.Parameters)
+		}
+		for i, parameter := range order {
+			typeName := caveat.Parameters[parameter]
+			if normalized := caveat.ParameterTypes[parameter]; normalized != "" {
+				typeName = normalized
+			}
+			writeSQLInsert(&b, "caveat_parameters", sqlString(caveat.Name), sqlString(parameter), sqlString(typeName), fmt.Sprint(i))
+		}
+	}
+
+	b.WriteString("\nCOMMIT;\n")
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,57 @@
+package spice2json
+
+// Stats summarizes the size and complexity of a schema
+type Stats struct {
+	Definitions              int `json:"definitions"`
+	Relations                int `json:"relations"`
+	Permissions              int `json:"permissions"`
+	Caveats                  int `json:"caveats"`
+	MaxPermissionDepth       int `json:"maxPermissionDepth"`
+	DefinitionsWithWildcards int `json:"definitionsWithWildcards"`
+	DefinitionsWithCaveats   int `json:"definitionsWithCaveats"`
+}
+
+// userSetDepth is the depth of the user set tree, a single relation or arrow has depth 1
+func userSetDepth(set *UserSet) int {
+	if set == nil {
+		return 0
+	}
+	depth := 0
+	for _, child := range set.Children {
+		depth = max(depth, userSetDepth(child))
+	}
+	return depth + 1
+}
+
+// Stats counts the definitions, relations, permissions and caveats of the schema
+func (s *Schema) Stats() *Stats {
+	stats := &Stats{
+		Definitions: len(s.Definitions),
+		Caveats:     len(s.Caveats),
+	}
+
+	for _, def := range s.Definitions {
+		stats.Relations += len(def.Relations)
+		stats.Permissions += len(def.Permissions)
+
+		wildcard, caveat := false, false
+		for _, r := range def.Relations {
+			for _, t := range r.Types {
+				wildcard = wildcard || t.Wildcard
+				caveat = caveat || t.Caveat != ""
+			}
+		}
+		if wildcard {
+			stats.DefinitionsWithWildcards++
+		}
+		if caveat {
+			stats.DefinitionsWithCaveats++
+		}
+
+		for _, p := range def.Permissions {
+			stats.MaxPermissionDepth = max(stats.MaxPermissionDepth, userSetDepth(p.UserSet))
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,51 @@
+package spice2json
+
+import (
+	"fmt"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+)
+
+// StreamDefinitions maps the definitions of the compiled schema one at a time and calls fn with
+// each in schema order, so large schemas can be written without holding all of them. Each
+// definition is mapped like MapSchema does, it stops at the first error of fn.
+func StreamDefinitions(schema *compiler.CompiledSchema, opts Options, fn func(*Definition) error) error {
+	var caveats []*Caveat
+	if opts.InlineCaveats {
+		var err error
+		if caveats, err = mapCaveats(schema, opts); err != nil {
+			return err
+		}
+	}
+	return streamDefinitions(schema, caveats, opts, fn)
+}
+
+// streamDefinitions is StreamDefinitions with the caveats already mapped for Options.InlineCaveats
+func streamDefinitions(schema *compiler.CompiledSchema, caveats []*Caveat, opts Options, fn func(*Definition) error) error {
+	kinds := memberKinds(schema, opts)
+	caveatsByName := map[string]*Caveat{}
+	for _, caveat := range caveats {
+		caveatsByName[caveat.Name] = caveat
+	}
+
+	for _, def := range schema.ObjectDefinitions {
+		if opts.RespectExclude && isExcluded(def.GetMetadata()) {
+			continue
+		}
+		o, err := mapDefinition(def, opts)
+		if err != nil {
+			return fmt.Errorf("failed to export %q: %w", def.Name, err)
+		}
+		annotateArrowKinds(o, kinds)
+		if opts.InlineCaveats {
+			inlineCaveats(o, caveatsByName)
+		}
+		if opts.OnDefinition != nil {
+			opts.OnDefinition(o)
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
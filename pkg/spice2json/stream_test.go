@@ -0,0 +1,91 @@
+package spice2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStreamDefinitions(t *testing.T) {
+	source := syntheticSchema(50, 0)
+	compiled, err := Compile("schema", source, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	err = StreamDefinitions(compiled, Options{}, func(def *Definition) error {
+		names = append(names, def.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"user"}
+	for i := 0; i < 50; i++ {
+		want = append(want, fmt.Sprintf("doc%d", i))
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("callback got %v, want each definition once in schema order %v", names, want)
+	}
+}
+
+func TestStreamDefinitionsStopsAtError(t *testing.T) {
+	compiled, err := Compile("schema", syntheticSchema(10, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	stop := fmt.Errorf("stop")
+	err = StreamDefinitions(compiled, Options{}, func(def *Definition) error {
+		calls++
+		if calls == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || calls != 3 {
+		t.Errorf("got error %v after %d calls, want %v after 3", err, calls, stop)
+	}
+}
+
+func TestWriteNDJSONFromMatchesWriteAs(t *testing.T) {
+	source := "caveat cz(zeta int, alpha int) { zeta > alpha }\n" + syntheticSchema(5, 0) +
+		"definition caveated {\n\trelation viewer: user with cz\n}\n"
+	opts := Options{Source: source}
+
+	compiled, err := Compile("schema", source, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed bytes.Buffer
+	if err := WriteNDJSONFrom(compiled, &streamed, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := ConvertFrom("schema", source, "app", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mapped bytes.Buffer
+	if err := WriteAs(schema, "ndjson", &mapped, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if streamed.String() != mapped.String() {
+		t.Errorf("WriteNDJSONFrom wrote\n%s\nWriteAs wrote\n%s", streamed.String(), mapped.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(streamed.String()), "\n")
+	var caveat Caveat
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &caveat); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(caveat.ParameterOrder, []string{"zeta", "alpha"}) {
+		t.Errorf("caveat parameter order is %v, want the declaration order [zeta alpha]", caveat.ParameterOrder)
+	}
+}
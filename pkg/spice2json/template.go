@@ -0,0 +1,44 @@
+package spice2json
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// TemplateFuncs are the functions available in templates of WriteTemplate besides the built
+// in ones. expression renders a user set and subject a relation type as schema DSL, subjects
+// returns the resolved subject types of a permission of a definition, like ResolveSubjects,
+// and qualified joins the namespace and name of a definition or relation type.
+func TemplateFuncs(schema *Schema) template.FuncMap {
+	definitions := definitionsByName(schema)
+	return template.FuncMap{
+		"expression": userSetExpression,
+		"subject":    relationTypeDSL,
+		"subjects": func(def *Definition, p *Permission) []string {
+			return resolvedSubjects(definitions, qualifiedName(def.Name, def.Namespace), p.Name)
+		},
+		"qualified": func(v any) (string, error) {
+			switch v := v.(type) {
+			case *Definition:
+				return qualifiedName(v.Name, v.Namespace), nil
+			case *RelationType:
+				return qualifiedName(v.Type, v.Namespace), nil
+			}
+			return "", fmt.Errorf("qualified expects a definition or relation type, got %T", v)
+		},
+	}
+}
+
+// WriteTemplate renders the text/template source with the schema as data and TemplateFuncs,
+// name is used in template errors
+func WriteTemplate(schema *Schema, w io.Writer, name string, source string) error {
+	tmpl, err := template.New(name).Funcs(TemplateFuncs(schema)).Parse(source)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(w, schema); err != nil {
+		return fmt.Errorf("unable to write schema template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package spice2json
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaKey matches the top level schema key of a validation file
+var schemaKey = regexp.MustCompile(`(?m)^schema:`)
+
+// ValidationFileSchema returns the schema embedded under the schema key of a zed validate or
+// playground yaml file, detected by its .yaml, .yml or .zaml extension or a top level schema key.
+// ok is false for anything else, such as a plain schema with a .zaml extension.
+func ValidationFileSchema(name string, source string) (schema string, ok bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".zaml":
+	default:
+		if !schemaKey.MatchString(source) {
+			return "", false
+		}
+	}
+
+	var file struct {
+		Schema string `yaml:"schema"`
+	}
+	if err := yaml.Unmarshal([]byte(source), &file); err != nil || file.Schema == "" {
+		return "", false
+	}
+	return file.Schema, true
+}
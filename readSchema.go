@@ -1,12 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/alsbury/spice2json/pkg/spice2json"
 	"github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/authzed-go/v1"
 	"github.com/authzed/grpcutil"
@@ -18,10 +26,137 @@ import (
 func readSchemaFromFile(inputFileName string) string {
 	b, err := os.ReadFile(inputFileName) // just pass the file name
 	if err != nil {
-		fmt.Print(err)
-		os.Exit(1)
+		exitWithError(err)
 	}
-	return string(b)
+	return readSchemaData(b, inputFileName)
+}
+
+// readSourceFile is readSchemaFromFile returning the error
+func readSourceFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return readSchemaData(b, path), nil
+}
+
+// readSchemaData decompresses the schema and extracts it from a validation file
+func readSchemaData(data []byte, source string) string {
+	schema := decompressSchema(data, source)
+	if embedded, ok := spice2json.ValidationFileSchema(strings.TrimSuffix(source, ".gz"), schema); ok {
+		return embedded
+	}
+	return schema
+}
+
+// decompressSchema gunzips the schema when it starts with the gzip magic number
+func decompressSchema(data []byte, source string) string {
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return string(data)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err == nil {
+		data, err = io.ReadAll(reader)
+	}
+	if err != nil {
+		exitWithError(ioError(fmt.Errorf("unable to decompress %s: %w", source, err)))
+	}
+	return string(data)
+}
+
+// readSchemaFromPath reads a schema file with the files it imports, all .zed files of a
+// directory or all files matching a glob pattern, checking multiple files for duplicate definitions, and returns the schema
+// and its source name
+func readSchemaFromPath(path string, namespace string) (string, string) {
+	return joinSourceFiles(readSourceFilesFromPath(path, namespace))
+}
+
+// readSourceFilesFromPath is readSchemaFromPath returning the files without joining them
+func readSourceFilesFromPath(path string, namespace string) []spice2json.SourceFile {
+	var files []spice2json.SourceFile
+	if strings.ContainsAny(path, "*?[") {
+		files = readSchemaFromGlob(path)
+	} else if info, err := os.Stat(path); err == nil && info.IsDir() {
+		files = readSchemaFromDir(path)
+	} else {
+		var err error
+		files, err = spice2json.ResolveImports(path, readSourceFile)
+		if err != nil {
+			var pathErr *fs.PathError
+			if !errors.As(err, &pathErr) {
+				err = compileError(err)
+			}
+			exitWithError(err)
+		}
+		if len(files) == 1 {
+			return []spice2json.SourceFile{{Name: path, Source: files[0].Source}}
+		}
+	}
+
+	if err := spice2json.CheckDuplicateDefinitions(files, namespace); err != nil {
+		exitWithError(compileError(err))
+	}
+	return files
+}
+
+// readSchemaFromDir reads all .zed and .zed.gz files below the directory, sorted by path
+func readSchemaFromDir(dir string) []spice2json.SourceFile {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && (filepath.Ext(path) == ".zed" || strings.HasSuffix(path, ".zed.gz")) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(files) == 0 {
+		exitWithError(ioError(errors.New("no .zed files found in " + dir)))
+	}
+	return readSourceFiles(files)
+}
+
+// readSchemaFromGlob reads the files matching the pattern, for shells that don't expand globs
+func readSchemaFromGlob(pattern string) []spice2json.SourceFile {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		exitWithError(usageError(err))
+	}
+	if len(files) == 0 {
+		exitWithError(ioError(errors.New("no files match " + pattern)))
+	}
+	return readSourceFiles(files)
+}
+
+// readSourceFiles reads the files sorted by path
+func readSourceFiles(files []string) []spice2json.SourceFile {
+	sort.Strings(files)
+
+	var sources []spice2json.SourceFile
+	for _, file := range files {
+		sources = append(sources, spice2json.SourceFile{Name: file, Source: readSchemaFromFile(file)})
+	}
+	return sources
+}
+
+// joinSourceFiles concatenates the files into one schema without import statements, with the
+// file names as source name
+func joinSourceFiles(files []spice2json.SourceFile) (string, string) {
+	if len(files) == 1 {
+		return spice2json.StripImports(files[0].Source), files[0].Name
+	}
+	var schema strings.Builder
+	names := make([]string, len(files))
+	for i, file := range files {
+		schema.WriteString(spice2json.StripImports(file.Source))
+		schema.WriteString("\n")
+		names[i] = file.Name
+	}
+	return schema.String(), strings.Join(names, ", ")
 }
 
 func readSchemaFromUrl(url string, key string) string {
@@ -36,20 +171,17 @@ func readSchemaFromUrl(url string, key string) string {
 
 	resp, err := request.Post(url)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(ioError(err))
 	}
 
 	if resp.StatusCode != 200 {
-		fmt.Println(resp.String())
-		os.Exit(1)
+		exitWithError(ioError(errors.New(resp.String())))
 	}
 
 	var data SchemaResponse
 	err = json.Unmarshal(resp.Bytes(), &data)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(ioError(err))
 	}
 	return data.SchemaText
 }
@@ -64,8 +196,7 @@ func readSchemaFromGrpc(host string, key string, insecureGrpc bool) string {
 	} else {
 		transport, err := grpcutil.WithSystemCerts(grpcutil.VerifyCA)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(ioError(err))
 		}
 		options = append(options, transport)
 		if key != "" {
@@ -75,13 +206,11 @@ func readSchemaFromGrpc(host string, key string, insecureGrpc bool) string {
 
 	client, err := authzed.NewClient(host, options...)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(ioError(err))
 	}
 	response, err := client.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(ioError(err))
 	}
 	return response.SchemaText
 }
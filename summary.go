@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alsbury/spice2json/pkg/spice2json"
+)
+
+// stderrIsTerminal reports whether stderr is a terminal rather than a pipe or file
+func stderrIsTerminal() bool {
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printSummary prints the number of converted definitions, relations, permissions and caveats
+// to stderr, only when it is a terminal and -quiet isn't set. The check mark is green unless
+// NO_COLOR is set.
+func printSummary(schema *spice2json.Schema) {
+	printStats(schema.Stats())
+}
+
+// printStats is printSummary with the counts of a schema that was written without holding it
+func printStats(stats *spice2json.Stats) {
+	if logLevel < verbosityNormal || !stderrIsTerminal() {
+		return
+	}
+	mark := "✓"
+	if os.Getenv("NO_COLOR") == "" {
+		mark = "\033[32m✓\033[0m"
+	}
+	fmt.Fprintf(os.Stderr, "%s %d definitions, %d relations, %d permissions, %d caveats\n",
+		mark, stats.Definitions, stats.Relations, stats.Permissions, stats.Caveats)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alsbury/spice2json/pkg/spice2json"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed schema/spice2json.schema.json
+var outputSchema string
+
+// validateSchema checks the json output against the published JSON Schema, returning the
+// json output along with the error so it can be shown
+func validateSchema(schema *spice2json.Schema) (string, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(spice2json.OutputSchemaURL, strings.NewReader(outputSchema)); err != nil {
+		return "", err
+	}
+	validator, err := compiler.Compile(spice2json.OutputSchemaURL)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := spice2json.WriteSchemaIndentTo(schema, &buf, "json", "  "); err != nil {
+		return "", err
+	}
+	var doc any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		return buf.String(), err
+	}
+	if err := validator.Validate(doc); err != nil {
+		return buf.String(), fmt.Errorf("output does not match %s: %#v", spice2json.OutputSchemaURL, err)
+	}
+	return "", nil
+}
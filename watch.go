@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alsbury/spice2json/pkg/spice2json"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long to wait for more changes before converting, editors often write
+// a file in several steps
+const watchDebounce = 200 * time.Millisecond
+
+// watchSchema converts the schema file or directory once and again whenever a schema file in
+// it changes, until interrupted. Each conversion runs this executable without -watch, so a
+// failed conversion prints its error and watching goes on.
+func watchSchema(path string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		exitWithError(ioError(err))
+	}
+	defer watcher.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		exitWithError(err)
+	}
+	// a directory is watched with its subdirectories. For a file only its directory and those
+	// of its imports are watched, editors replace files on save rather than writing them.
+	var files map[string]bool
+	if info.IsDir() {
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return watcher.Add(p)
+			}
+			return nil
+		})
+	} else {
+		files, err = watchFile(watcher, path)
+	}
+	if err != nil {
+		exitWithError(ioError(err))
+	}
+
+	args := watchArgs(os.Args[1:])
+	runConversion(args)
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !info.IsDir() {
+				if files[filepath.Clean(event.Name)] {
+					timer.Reset(watchDebounce)
+				}
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if created, err := os.Stat(event.Name); err == nil && created.IsDir() {
+					_ = watcher.Add(event.Name)
+				}
+			}
+			if isSchemaFile(event.Name) {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintln(os.Stderr, "watch error: "+err.Error())
+		case <-timer.C:
+			runConversion(args)
+			// the imports may have changed with the file
+			if !info.IsDir() {
+				if files, err = watchFile(watcher, path); err != nil {
+					fmt.Fprintln(os.Stderr, "watch error: "+err.Error())
+				}
+			}
+		}
+	}
+}
+
+// watchFile watches the directory of the schema file and of each file it imports, without
+// their subdirectories, and returns the files whose changes trigger a conversion. Imports that
+// can't be resolved are left to the conversion to report.
+func watchFile(watcher *fsnotify.Watcher, path string) (map[string]bool, error) {
+	files := map[string]bool{filepath.Clean(path): true}
+	if imported, err := spice2json.ResolveImports(path, readSourceFile); err == nil {
+		for _, file := range imported {
+			files[filepath.Clean(file.Name)] = true
+		}
+	}
+	for file := range files {
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+// runConversion runs this executable with the arguments and prints a timestamped status line
+// to stderr
+func runConversion(args []string) {
+	executable, err := os.Executable()
+	if err != nil {
+		exitWithError(err)
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+
+	status := "converted"
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status = "failed, watching for changes"
+	} else if err != nil {
+		exitWithError(err)
+	}
+	printInfo("%s %s", time.Now().Format("15:04:05"), status)
+}
+
+// watchArgs removes -watch from the command line arguments
+func watchArgs(args []string) []string {
+	var kept []string
+	for _, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(arg, "-") && (name == "watch" || strings.HasPrefix(name, "watch=")) {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
+
+func isSchemaFile(name string) bool {
+	return filepath.Ext(name) == ".zed" || filepath.Ext(name) == ".zaml" || strings.HasSuffix(name, ".zed.gz")
+}